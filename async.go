@@ -0,0 +1,217 @@
+package log
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what AsyncWriter.Write does when the queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the background writer made room.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the record that is being written.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest queued record to make room.
+	OverflowDropOldest
+	// OverflowSample blocks for every SampleRate-th record and drops the others.
+	OverflowSample
+)
+
+type AsyncConfig struct {
+	// QueueSize is the number of records that can be queued, 1024 by default.
+	QueueSize  int
+	Policy     OverflowPolicy
+	SampleRate int
+	// ErrorHandler receives the errors of the underlying writer, which can
+	// not be returned to the caller of Write.
+	ErrorHandler func(error)
+}
+
+// AsyncWriter queues records and writes them to the underlying writer in a
+// background goroutine, so that slow writers do not block the caller of Info
+// and Debug. Call Close to drain the queue before the program exits, records
+// written concurrently with Close may be lost.
+type AsyncWriter struct {
+	// accessed atomically, first to keep them aligned on 32 bit platforms
+	dropped   uint64
+	overflows uint64
+
+	writer    io.Writer
+	config    AsyncConfig
+	queue     chan *[]byte
+	flushes   chan chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+	stopped   chan struct{}
+}
+
+func NewAsyncWriter(writer io.Writer, config AsyncConfig) *AsyncWriter {
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1024
+	}
+	if config.SampleRate <= 0 {
+		config.SampleRate = 1
+	}
+	asyncWriter := &AsyncWriter{
+		writer:  writer,
+		config:  config,
+		queue:   make(chan *[]byte, config.QueueSize),
+		flushes: make(chan chan struct{}),
+		closed:  make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go asyncWriter.run()
+	return asyncWriter
+}
+
+// Write queues a copy of record. It only returns an error after Close.
+func (aw *AsyncWriter) Write(record []byte) (int, error) {
+	select {
+	case <-aw.closed:
+		return 0, os.ErrClosed
+	default:
+	}
+
+	buffer := getBuffer()
+	*buffer = append(*buffer, record...)
+	select {
+	case aw.queue <- buffer:
+		return len(record), nil
+	default:
+	}
+
+	switch aw.config.Policy {
+	case OverflowDropNewest:
+		aw.drop(buffer)
+	case OverflowDropOldest:
+		for {
+			select {
+			case aw.queue <- buffer:
+				return len(record), nil
+			default:
+			}
+			select {
+			case oldest := <-aw.queue:
+				aw.drop(oldest)
+			default:
+			}
+		}
+	case OverflowSample:
+		if atomic.AddUint64(&aw.overflows, 1)%uint64(aw.config.SampleRate) != 0 {
+			aw.drop(buffer)
+			break
+		}
+		return aw.enqueue(buffer)
+	default:
+		return aw.enqueue(buffer)
+	}
+	return len(record), nil
+}
+
+func (aw *AsyncWriter) enqueue(record *[]byte) (int, error) {
+	size := len(*record)
+	select {
+	case aw.queue <- record:
+		return size, nil
+	case <-aw.closed:
+		putBuffer(record)
+		return 0, os.ErrClosed
+	}
+}
+
+// Dropped returns the number of records that were dropped because the queue
+// was full.
+func (aw *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&aw.dropped)
+}
+
+// Flush waits until all records queued before the call have been written or
+// the context is done.
+func (aw *AsyncWriter) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case aw.flushes <- done:
+	case <-aw.stopped:
+		return os.ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting records and waits until the queue is drained or the
+// context is done. It does not close the underlying writer.
+func (aw *AsyncWriter) Close(ctx context.Context) error {
+	aw.closeOnce.Do(func() { close(aw.closed) })
+	select {
+	case <-aw.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (aw *AsyncWriter) run() {
+	defer close(aw.stopped)
+	for {
+		select {
+		case record := <-aw.queue:
+			aw.write(record)
+		case done := <-aw.flushes:
+			aw.writeQueued(len(aw.queue))
+			close(done)
+		case <-aw.closed:
+			aw.drain()
+			return
+		}
+	}
+}
+
+// writeQueued writes up to count queued records, the records queued when a
+// flush was requested. Records queued later are left to run, so that steady
+// writers can not keep Flush from returning.
+func (aw *AsyncWriter) writeQueued(count int) {
+	for ; count > 0; count-- {
+		select {
+		case record := <-aw.queue:
+			aw.write(record)
+		default: // dropped by OverflowDropOldest
+			return
+		}
+	}
+}
+
+// drain writes the queued records until the queue is empty.
+func (aw *AsyncWriter) drain() {
+	for {
+		select {
+		case record := <-aw.queue:
+			aw.write(record)
+		default:
+			return
+		}
+	}
+}
+
+func (aw *AsyncWriter) write(record *[]byte) {
+	_, err := aw.writer.Write(*record)
+	putBuffer(record)
+	if err != nil && aw.config.ErrorHandler != nil {
+		aw.config.ErrorHandler(err)
+	}
+}
+
+func (aw *AsyncWriter) drop(record *[]byte) {
+	putBuffer(record)
+	atomic.AddUint64(&aw.dropped, 1)
+}
@@ -0,0 +1,181 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flowpl/log"
+)
+
+// gatedWriter blocks every write until the gate is opened and reports when
+// the first write started.
+type gatedWriter struct {
+	mutex   sync.Mutex
+	output  bytes.Buffer
+	gate    chan struct{}
+	started chan struct{}
+	once    sync.Once
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{gate: make(chan struct{}), started: make(chan struct{})}
+}
+
+func (gw *gatedWriter) Write(record []byte) (int, error) {
+	gw.once.Do(func() { close(gw.started) })
+	<-gw.gate
+	gw.mutex.Lock()
+	defer gw.mutex.Unlock()
+	return gw.output.Write(record)
+}
+
+func (gw *gatedWriter) String() string {
+	gw.mutex.Lock()
+	defer gw.mutex.Unlock()
+	return gw.output.String()
+}
+
+func writeRecords(writer *log.AsyncWriter, names ...string) {
+	for _, name := range names {
+		writer.Write([]byte(name + "\n"))
+	}
+}
+
+func TestAsyncWriter_FlushShouldWriteAllQueuedRecords(t *testing.T) {
+	output := newGatedWriter()
+	close(output.gate)
+	writer := log.NewAsyncWriter(output, log.AsyncConfig{})
+	logger := log.NewLogger(&log.Config{Encoder: log.TextEncoder{}, Writer: writer, FunctionName: "main", DateFormat: "2006"})
+
+	for i := 0; i < 100; i++ {
+		logger.Info("message", nil)
+	}
+	if err := writer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed with error: %s", err.Error())
+	}
+
+	if lines := strings.Count(output.String(), "\tINFO\tmain\tmessage\t"); lines != 100 {
+		t.Errorf("expected 100 records after Flush, actual: %d", lines)
+	}
+}
+
+type slowWriter struct{}
+
+func (slowWriter) Write(record []byte) (int, error) {
+	time.Sleep(10 * time.Microsecond)
+	return len(record), nil
+}
+
+func TestAsyncWriter_FlushShouldReturnWhileOthersKeepWriting(t *testing.T) {
+	writer := log.NewAsyncWriter(slowWriter{}, log.AsyncConfig{QueueSize: 16})
+	stop := make(chan struct{})
+	var writers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					writeRecords(writer, "record")
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(stop)
+		writers.Wait()
+		writer.Close(context.Background())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := writer.Flush(ctx); err != nil {
+		t.Errorf("expected Flush to return after the records queued before it, actual: %s", err)
+	}
+}
+
+func TestAsyncWriter_DropNewestShouldDropRecordsIfTheQueueIsFull(t *testing.T) {
+	output := newGatedWriter()
+	writer := log.NewAsyncWriter(output, log.AsyncConfig{QueueSize: 2, Policy: log.OverflowDropNewest})
+
+	writeRecords(writer, "first")
+	<-output.started
+	writeRecords(writer, "second", "third", "fourth", "fifth")
+	close(output.gate)
+	writer.Close(context.Background())
+
+	if output.String() != "first\nsecond\nthird\n" || writer.Dropped() != 2 {
+		t.Errorf("expected first three records and 2 dropped, actual: %q, %d dropped", output.String(), writer.Dropped())
+	}
+}
+
+func TestAsyncWriter_DropOldestShouldMakeRoomForNewRecords(t *testing.T) {
+	output := newGatedWriter()
+	writer := log.NewAsyncWriter(output, log.AsyncConfig{QueueSize: 2, Policy: log.OverflowDropOldest})
+
+	writeRecords(writer, "first")
+	<-output.started
+	writeRecords(writer, "second", "third", "fourth", "fifth")
+	close(output.gate)
+	writer.Close(context.Background())
+
+	if output.String() != "first\nfourth\nfifth\n" || writer.Dropped() != 2 {
+		t.Errorf("expected first and last two records and 2 dropped, actual: %q, %d dropped", output.String(), writer.Dropped())
+	}
+}
+
+func TestAsyncWriter_SampleShouldKeepEveryNthOverflowingRecord(t *testing.T) {
+	output := newGatedWriter()
+	writer := log.NewAsyncWriter(output, log.AsyncConfig{QueueSize: 1, Policy: log.OverflowSample, SampleRate: 2})
+
+	writeRecords(writer, "first")
+	<-output.started
+	writeRecords(writer, "second", "third")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(output.gate)
+	}()
+	// the second overflowing record blocks until there is room
+	writeRecords(writer, "fourth")
+	writer.Close(context.Background())
+
+	if output.String() != "first\nsecond\nfourth\n" || writer.Dropped() != 1 {
+		t.Errorf("expected every second overflowing record and 1 dropped, actual: %q, %d dropped", output.String(), writer.Dropped())
+	}
+}
+
+func TestAsyncWriter_CloseShouldReturnTheContextErrorIfTheDeadlinePasses(t *testing.T) {
+	output := newGatedWriter()
+	writer := log.NewAsyncWriter(output, log.AsyncConfig{})
+	writeRecords(writer, "stuck")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := writer.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, actual: %v", err)
+	}
+	close(output.gate)
+
+	if _, err := writer.Write([]byte("late\n")); err != os.ErrClosed {
+		t.Errorf("expected os.ErrClosed after Close, actual: %v", err)
+	}
+}
+
+func TestAsyncWriter_ShouldPassWriteErrorsToTheErrorHandler(t *testing.T) {
+	errors := make(chan error, 1)
+	writer := log.NewAsyncWriter(&failingWriter{err: os.ErrPermission}, log.AsyncConfig{ErrorHandler: func(err error) { errors <- err }})
+	writeRecords(writer, "record")
+	writer.Close(context.Background())
+
+	if err := <-errors; err != os.ErrPermission {
+		t.Errorf("expected os.ErrPermission, actual: %v", err)
+	}
+}
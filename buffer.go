@@ -0,0 +1,84 @@
+package log
+
+import (
+	"sort"
+	"sync"
+)
+
+// buffers larger than this are not returned to the pool so that a single huge
+// record does not pin its memory for the lifetime of the program
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, 0, 1024)
+		return &buffer
+	},
+}
+
+func getBuffer() *[]byte {
+	buffer := bufferPool.Get().(*[]byte)
+	*buffer = (*buffer)[:0]
+	return buffer
+}
+
+func putBuffer(buffer *[]byte) {
+	if cap(*buffer) <= maxPooledBufferSize {
+		bufferPool.Put(buffer)
+	}
+}
+
+// fieldBuffer collects the tags of a single call before they are merged with
+// the cached tags of the logger.
+type fieldBuffer struct {
+	fields Fields
+}
+
+var fieldBufferPool = sync.Pool{
+	New: func() interface{} {
+		return &fieldBuffer{fields: make(Fields, 0, 16)}
+	},
+}
+
+func getFieldBuffer() *fieldBuffer {
+	buffer := fieldBufferPool.Get().(*fieldBuffer)
+	buffer.fields = buffer.fields[:0]
+	return buffer
+}
+
+func putFieldBuffer(buffer *fieldBuffer) {
+	if cap(buffer.fields) <= 256 {
+		// drop references to values so that they can be collected
+		fields := buffer.fields[:cap(buffer.fields)]
+		for i := range fields {
+			fields[i] = Field{}
+		}
+		fieldBufferPool.Put(buffer)
+	}
+}
+
+func (buffer *fieldBuffer) Len() int {
+	return len(buffer.fields)
+}
+
+func (buffer *fieldBuffer) Less(i, j int) bool {
+	return buffer.fields[i].Key < buffer.fields[j].Key
+}
+
+func (buffer *fieldBuffer) Swap(i, j int) {
+	buffer.fields[i], buffer.fields[j] = buffer.fields[j], buffer.fields[i]
+}
+
+// sortUnique sorts the fields by key and keeps only the last value for
+// duplicate keys, without allocating.
+func (buffer *fieldBuffer) sortUnique() {
+	sort.Stable(buffer)
+	unique := buffer.fields[:0]
+	for i := range buffer.fields {
+		if i+1 < len(buffer.fields) && buffer.fields[i+1].Key == buffer.fields[i].Key {
+			continue
+		}
+		unique = append(unique, buffer.fields[i])
+	}
+	buffer.fields = unique
+}
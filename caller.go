@@ -0,0 +1,87 @@
+package log
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const maxStackDepth = 64
+
+// capture adds the caller and, if the context holds an error, the stack of
+// the code that called the method of a level to the fields, unless the
+// context has these tags already.
+func (log Log) capture(fields *fieldBuffer) (Caller, string) {
+	// frames above capture: write, the method of the level, the caller
+	skip := 3 + log.config.CallerSkip
+	var caller Caller
+	var stack string
+	if log.config.CaptureCaller {
+		if callers := captureStack(skip, 1); len(callers) > 0 {
+			caller = callers[0]
+			fields.appendMissing(Object("caller",
+				String("file", caller.File),
+				Int("line", caller.Line),
+				String("function", caller.Function),
+			))
+		}
+	}
+	if log.config.CaptureStack && hasErrorTag(fields.fields) {
+		stack = formatStack(captureStack(skip, maxStackDepth))
+		fields.appendMissing(String("stack", stack))
+	}
+	return caller, stack
+}
+
+// captureStack returns up to depth callers, starting skip frames above the
+// caller of captureStack.
+func captureStack(skip int, depth int) []Caller {
+	pcs := make([]uintptr, depth)
+	count := runtime.Callers(skip+2, pcs)
+	return callersOf(pcs[:count], depth)
+}
+
+// callersOf resolves up to depth callers of the return program counters of
+// runtime.Callers.
+func callersOf(pcs []uintptr, depth int) []Caller {
+	if len(pcs) == 0 {
+		return nil
+	}
+	callers := make([]Caller, 0, len(pcs))
+	frames := runtime.CallersFrames(pcs)
+	for len(callers) < depth {
+		frame, more := frames.Next()
+		callers = append(callers, Caller{File: frame.File, Line: frame.Line, Function: frame.Function})
+		if !more {
+			break
+		}
+	}
+	return callers
+}
+
+// formatStack renders callers like a goroutine stack in a panic.
+func formatStack(callers []Caller) string {
+	lines := make([]string, len(callers))
+	for i, caller := range callers {
+		lines[i] = caller.Function + "\n\t" + caller.File + ":" + strconv.Itoa(caller.Line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func hasErrorTag(fields Fields) bool {
+	for _, field := range fields {
+		if field.Key == "error" && field.Value.Kind() == KindError && field.Value.Err() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (buffer *fieldBuffer) appendMissing(field Field) {
+	for _, existing := range buffer.fields {
+		if existing.Key == field.Key {
+			return
+		}
+	}
+	buffer.fields = append(buffer.fields, field)
+}
@@ -0,0 +1,124 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/flowpl/log"
+)
+
+type callerRecord struct {
+	Caller struct {
+		File     string
+		Line     int
+		Function string
+	}
+	Stack string
+}
+
+func decodeCallerRecord(t *testing.T, output *bytes.Buffer) callerRecord {
+	line, _ := output.ReadBytes('\n')
+	record := callerRecord{}
+	if err := json.Unmarshal(line, &record); err != nil {
+		t.Fatalf("invalid json record %q: %s", line, err)
+	}
+	return record
+}
+
+func currentLine() (string, int) {
+	_, file, line, _ := runtime.Caller(1)
+	return file, line
+}
+
+func TestLog_InfoShouldCaptureTheCaller(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{Level: log.LEVEL_DEBUG, Encoder: log.JsonEncoder{}, Writer: output, CaptureCaller: true})
+
+	file, line := currentLine()
+	logger.Info("info", nil)
+	logger.Debug("debug", nil)
+	logger.DebugFunc(func() (string, interface{}) { return "debug func", nil })
+
+	for i, message := range []string{"info", "debug", "debug func"} {
+		record := decodeCallerRecord(t, output)
+		if record.Caller.File != file || record.Caller.Line != line+i+1 {
+			t.Errorf("%s: expected caller %s:%d, actual: %s:%d", message, file, line+i+1, record.Caller.File, record.Caller.Line)
+		}
+		if record.Caller.Function != "github.com/flowpl/log_test.TestLog_InfoShouldCaptureTheCaller" {
+			t.Errorf("%s: unexpected caller function %q", message, record.Caller.Function)
+		}
+	}
+}
+
+type wrapper struct {
+	logger log.Logger
+}
+
+func (w wrapper) Warn(message string) error {
+	return w.logger.Info("WARN: "+message, nil)
+}
+
+func TestLog_InfoShouldSkipTheFramesOfWrappers(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{Encoder: log.JsonEncoder{}, Writer: output, CaptureCaller: true, CallerSkip: 1})
+	child, _ := logger.ChildLogger("child", nil)
+
+	file, line := currentLine()
+	wrapper{child}.Warn("message")
+
+	record := decodeCallerRecord(t, output)
+	if record.Caller.File != file || record.Caller.Line != line+1 {
+		t.Errorf("expected caller %s:%d, actual: %s:%d", file, line+1, record.Caller.File, record.Caller.Line)
+	}
+}
+
+func TestLog_InfoShouldCaptureTheStackOnlyForErrors(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{Encoder: log.JsonEncoder{}, Writer: output, CaptureStack: true})
+
+	logger.Info("message", errors.New("failed"))
+	withError := decodeCallerRecord(t, output)
+	logger.Info("message", log.String("error", "not an error"))
+	withoutError := decodeCallerRecord(t, output)
+
+	if !strings.HasPrefix(withError.Stack, "github.com/flowpl/log_test.TestLog_InfoShouldCaptureTheStackOnlyForErrors\n\t") {
+		t.Errorf("expected the stack to start at the test, actual: %q", withError.Stack)
+	}
+	if withError.Caller.File != "" {
+		t.Errorf("expected no caller without CaptureCaller, actual: %+v", withError.Caller)
+	}
+	if withoutError.Stack != "" {
+		t.Errorf("expected no stack without error, actual: %q", withoutError.Stack)
+	}
+}
+
+func TestLog_InfoShouldPassTheCallerAndStackToFormatters(t *testing.T) {
+	formatter := new(recordingFormatter)
+	logger := log.NewLogger(&log.Config{Formatter: formatter, Writer: new(bytes.Buffer), CaptureCaller: true, CaptureStack: true})
+
+	file, line := currentLine()
+	logger.Info("message", errors.New("failed"))
+
+	record := formatter.records[0]
+	if record.Caller.File != file || record.Caller.Line != line+1 {
+		t.Errorf("expected caller %s:%d, actual: %+v", file, line+1, record.Caller)
+	}
+	if stack, _ := record.Fields.Get("stack"); record.Stack == "" || stack.String() != record.Stack {
+		t.Errorf("expected the stack in the record and its fields, actual: %q", record.Stack)
+	}
+}
+
+func TestLog_InfoShouldKeepCallerTagsOfTheContext(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{Encoder: log.JsonEncoder{}, Writer: output, CaptureCaller: true})
+
+	logger.Info("message", log.Fields{log.String("caller", "custom")})
+
+	if !strings.Contains(output.String(), `"caller":"custom"`) {
+		t.Errorf("expected the caller of the context, actual: %q", output.String())
+	}
+}
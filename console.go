@@ -0,0 +1,191 @@
+package log
+
+import (
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	colorReset   = "\x1b[0m"
+	colorDim     = "\x1b[2m"
+	colorRed     = "\x1b[1;31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorCyan    = "\x1b[36m"
+	messageWidth = 40
+)
+
+// ConsoleFormatter renders records for humans reading a terminal: a dimmed
+// time, the colored and padded level, the function and the message, followed
+// by the tags as key=value pairs. Further lines of the message and tags with
+// multi-line values are printed below the record, indented. Control
+// characters are escaped, so that escape sequences do not reach the terminal.
+type ConsoleFormatter struct {
+	Color bool
+}
+
+// NewConsoleFormatter enables colors if output is a terminal, unless the
+// NO_COLOR environment variable is set or TERM is dumb.
+func NewConsoleFormatter(output io.Writer) ConsoleFormatter {
+	return ConsoleFormatter{Color: isColorTerminal(output)}
+}
+
+func (cf ConsoleFormatter) Format(output []byte, record *Record) []byte {
+	output = cf.appendColored(output, colorDim, record.Time.Format(record.DateFormat))
+	output = append(output, ' ')
+	output = cf.appendColored(output, levelColor(record.Level), record.Level)
+	output = appendPadding(output, 5-utf8.RuneCountInString(record.Level))
+	output = append(output, ' ')
+	if len(record.Function) > 0 {
+		output = cf.appendColored(output, colorCyan, record.Function)
+		output = append(output, ':', ' ')
+	}
+	lines := consoleLines(record.Message)
+	start := len(output)
+	output = cf.appendColored(output, "", lines[0])
+	messageLength := utf8.RuneCount(output[start:])
+
+	var multiLine []byte
+	for _, line := range lines[1:] {
+		multiLine = append(multiLine, "\n        "...)
+		multiLine = cf.appendColored(multiLine, "", line)
+	}
+	padded := false
+	for _, field := range record.Fields.sorted() {
+		if field.Key == "function" || len(field.Key) == 0 {
+			continue
+		}
+		if !padded {
+			output = appendPadding(output, messageWidth-messageLength)
+			padded = true
+		}
+		output, multiLine = cf.appendField(output, multiLine, "", field)
+	}
+	return append(output, multiLine...)
+}
+
+// appendField appends single-line tags to dst and multi-line tags to
+// multiLine.
+func (cf ConsoleFormatter) appendField(dst []byte, multiLine []byte, prefix string, field Field) ([]byte, []byte) {
+	if field.Value.Kind() == KindObject && len(field.Value.Object()) > 0 {
+		for _, nested := range field.Value.Object() {
+			dst, multiLine = cf.appendField(dst, multiLine, prefix+field.Key+".", nested)
+		}
+		return dst, multiLine
+	}
+
+	valueColor := ""
+	if field.Key == "error" || field.Value.Kind() == KindError {
+		valueColor = colorRed
+	}
+	value := field.Value.String()
+	if strings.Contains(value, "\n") {
+		multiLine = cf.appendMultiLine(multiLine, prefix+field.Key, value, valueColor)
+	} else {
+		dst = append(dst, ' ')
+		dst = cf.appendColored(dst, colorDim, prefix+field.Key+"=")
+		if !cf.Color || valueColor == "" {
+			dst = appendLogfmtString(dst, value)
+		} else {
+			dst = append(dst, valueColor...)
+			dst = appendLogfmtString(dst, value)
+			dst = append(dst, colorReset...)
+		}
+	}
+	if err := field.Value.Err(); field.Value.Kind() == KindError && err != nil {
+		if stack := describeError(err).stack; len(stack) > 0 {
+			multiLine = cf.appendMultiLine(multiLine, prefix+field.Key+".stack", stack, "")
+		}
+	}
+	return dst, multiLine
+}
+
+func (cf ConsoleFormatter) appendMultiLine(dst []byte, key string, value string, color string) []byte {
+	dst = append(dst, "\n    "...)
+	dst = cf.appendColored(dst, colorDim, key+":")
+	for _, line := range consoleLines(value) {
+		dst = append(dst, "\n        "...)
+		dst = cf.appendColored(dst, color, line)
+	}
+	return dst
+}
+
+func (cf ConsoleFormatter) appendColored(dst []byte, color string, s string) []byte {
+	if !cf.Color || color == "" {
+		return appendConsoleText(dst, s)
+	}
+	dst = append(dst, color...)
+	dst = appendConsoleText(dst, s)
+	return append(dst, colorReset...)
+}
+
+// consoleLines splits a message or value into lines, dropping trailing line
+// breaks.
+func consoleLines(s string) []string {
+	lines := strings.Split(strings.TrimRight(s, "\r\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// appendConsoleText appends s with control characters escaped, so that
+// escape sequences in messages and tags do not reach the terminal. Tabs are
+// kept.
+func appendConsoleText(dst []byte, s string) []byte {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '\t':
+			dst = append(dst, '\t')
+		case r < 0x20 || r == 0x7f:
+			dst = append(dst, '\\', 'x', hexDigits[r>>4], hexDigits[r&0xF])
+		case r >= 0x80 && r <= 0x9f:
+			dst = append(dst, '\\', 'u', '0', '0', hexDigits[r>>4], hexDigits[r&0xF])
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, '\\', 'x', hexDigits[s[i]>>4], hexDigits[s[i]&0xF])
+		default:
+			dst = append(dst, s[i:i+size]...)
+		}
+		i += size
+	}
+	return dst
+}
+
+func levelColor(level string) string {
+	switch level {
+	case LEVEL_INFO:
+		return colorGreen
+	case LEVEL_DEBUG:
+		return colorBlue
+	case LEVEL_TRACE:
+		return colorDim
+	case LEVEL_WARN:
+		return colorYellow
+	case LEVEL_ERROR, LEVEL_FATAL:
+		return colorRed
+	}
+	return ""
+}
+
+func appendPadding(dst []byte, width int) []byte {
+	for ; width > 0; width-- {
+		dst = append(dst, ' ')
+	}
+	return dst
+}
+
+func isColorTerminal(output io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	file, ok := output.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
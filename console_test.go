@@ -0,0 +1,134 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func formatConsole(formatter ConsoleFormatter, level string, message string, fields Fields, dateFormat string) string {
+	function, _ := fields.Get("function")
+	record := &Record{Time: testTime, Level: level, Message: message, Function: function.String(), Fields: fields, DateFormat: dateFormat}
+	return string(formatter.Format(nil, record))
+}
+
+func TestConsoleFormatterShouldAlignLevelsAndTags(t *testing.T) {
+	formatter := ConsoleFormatter{}
+	info := formatConsole(formatter, "INFO", "started", Fields{String("function", "main"), Int("port", 8080)}, "15:04")
+	debug := formatConsole(formatter, "DEBUG", "listening", Fields{String("function", "main"), String("address", "0.0.0.0:8080")}, "15:04")
+
+	now := testTime.Format("15:04")
+	expectedInfo := now + " INFO  main: started" + strings.Repeat(" ", 33) + " port=8080"
+	if info != expectedInfo {
+		t.Errorf("expected %q, actual: %q", expectedInfo, info)
+	}
+	expectedDebug := now + " DEBUG main: listening" + strings.Repeat(" ", 31) + " address=0.0.0.0:8080"
+	if debug != expectedDebug {
+		t.Errorf("expected %q, actual: %q", expectedDebug, debug)
+	}
+}
+
+func TestConsoleFormatterShouldIndentMultiLineValuesBelowTheRecord(t *testing.T) {
+	result := formatConsole(ConsoleFormatter{}, "INFO", "failed", Fields{
+		String("query", "SELECT *\nFROM users\n"),
+		String("table", "users"),
+	}, "15:04")
+
+	lines := strings.Split(result, "\n")
+	expected := []string{"    query:", "        SELECT *", "        FROM users"}
+	if len(lines) != 4 || !strings.HasSuffix(lines[0], " table=users") || strings.Contains(lines[0], "query") {
+		t.Fatalf("unexpected record %q", result)
+	}
+	for i, line := range expected {
+		if lines[i+1] != line {
+			t.Errorf("expected line %d to be %q, actual: %q", i+1, line, lines[i+1])
+		}
+	}
+}
+
+func TestConsoleFormatterShouldIndentMultiLineMessages(t *testing.T) {
+	result := formatConsole(ConsoleFormatter{}, "INFO", "failed\r\nretrying\n", Fields{
+		String("function", "main"),
+		String("query", "SELECT *\nFROM users"),
+		Int("attempt", 2),
+	}, "15:04")
+
+	expected := []string{
+		testTime.Format("15:04") + " INFO  main: failed" + strings.Repeat(" ", 34) + " attempt=2",
+		"        retrying",
+		"    query:",
+		"        SELECT *",
+		"        FROM users",
+	}
+	if result != strings.Join(expected, "\n") {
+		t.Errorf("expected %q, actual: %q", strings.Join(expected, "\n"), result)
+	}
+}
+
+func TestConsoleFormatterShouldEscapeControlCharacters(t *testing.T) {
+	result := formatConsole(ConsoleFormatter{Color: true}, "INFO", "\x1b[2Jcleared\tscreen \u009b31m", Fields{
+		String("function", "\x1b]0;title\x07"),
+		String("stack", "line\x1b[1m\nnext \xff"),
+	}, "15:04")
+
+	if strings.Contains(strings.NewReplacer(colorDim, "", colorGreen, "", colorCyan, "", colorReset, "").Replace(result), "\x1b") || strings.Contains(result, "\u009b") {
+		t.Errorf("expected escape sequences to be escaped, actual: %q", result)
+	}
+	for _, part := range []string{`\x1b[2Jcleared` + "\t" + `screen \u009b31m`, `\x1b]0;title\x07`, `line\x1b[1m`, `next \xff`} {
+		if !strings.Contains(result, part) {
+			t.Errorf("expected %q in %q", part, result)
+		}
+	}
+}
+
+func TestConsoleFormatterShouldColorLevelsAndErrors(t *testing.T) {
+	result := formatConsole(ConsoleFormatter{Color: true}, "INFO", "failed", Fields{
+		Err("error", errors.New("connection refused")),
+		Int("attempt", 3),
+	}, "15:04")
+
+	for _, expected := range []string{
+		colorDim + testTime.Format("15:04") + colorReset,
+		colorGreen + "INFO" + colorReset,
+		colorRed + `"connection refused"` + colorReset,
+		colorDim + "attempt=" + colorReset + "3",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("expected %q in %q", expected, result)
+		}
+	}
+}
+
+func TestConsoleFormatterShouldNotColorWithoutColor(t *testing.T) {
+	result := formatConsole(ConsoleFormatter{}, "DEBUG", "message", Fields{Err("error", errors.New("failed"))}, "15:04")
+
+	if strings.Contains(result, "\x1b[") {
+		t.Errorf("expected no escape sequences, actual: %q", result)
+	}
+}
+
+func TestNewConsoleFormatterShouldDisableColorForNonTerminals(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "output.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if NewConsoleFormatter(file).Color {
+		t.Error("expected no color for a regular file")
+	}
+	if NewConsoleFormatter(new(bytes.Buffer)).Color {
+		t.Error("expected no color for a buffer")
+	}
+}
+
+func TestNewConsoleFormatterShouldDisableColorIfNoColorIsSet(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	if NewConsoleFormatter(os.Stdout).Color {
+		t.Error("expected no color with NO_COLOR set")
+	}
+}
@@ -0,0 +1,58 @@
+package log
+
+// Context combines several context items into the context of a single call
+// or child logger. Items are merged from left to right and later items
+// replace the tags of earlier ones with the same key, all of them replace the
+// tags of the logger:
+//
+//	logger.Info("query failed", log.With(err, requestTags, "attempt", 3))
+//
+// An item is a map, struct, error (the error tag), fmt.Stringer (the context
+// tag), Field, Fields, nested Context or a string key followed by its value.
+type Context []interface{}
+
+// With returns the context of items.
+func With(items ...interface{}) Context {
+	return Context(items)
+}
+
+type MissingContextValue string
+
+func (err MissingContextValue) Error() string {
+	return "missing value for context key \"" + string(err) + "\""
+}
+
+func (buffer *fieldBuffer) appendContextItems(context Context) error {
+	for i := 0; i < len(context); i++ {
+		if key, ok := context[i].(string); ok {
+			if i+1 == len(context) {
+				return MissingContextValue(key)
+			}
+			i++
+			buffer.fields = append(buffer.fields, Any(key, context[i]))
+			continue
+		}
+		if err := buffer.appendContext(context[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mergeContextItems(tags Fields, context Context) (Fields, error) {
+	var err error
+	for i := 0; i < len(context); i++ {
+		if key, ok := context[i].(string); ok {
+			if i+1 == len(context) {
+				return nil, MissingContextValue(key)
+			}
+			i++
+			tags = tags.set(Any(key, context[i]))
+			continue
+		}
+		if tags, err = mergeTags(tags, context[i]); err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
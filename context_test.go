@@ -0,0 +1,109 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/flowpl/log"
+)
+
+func newContextLogger(dfo *DummyFormatOutput) log.Logger {
+	return log.NewLogger(&log.Config{
+		Level:        log.LEVEL_DEBUG,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		Tags:         log.Fields{log.String("service", "orders")},
+	})
+}
+
+func TestLog_InfoShouldMergeContextItemsInOrder(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	logger := newContextLogger(dfo)
+	err := errors.New("failed")
+
+	logger.Info("message", log.With(
+		err,
+		map[string]string{"request": "abc", "user": "first"},
+		arbitraryStringer{"exportedValue", "value"},
+		"user", 42,
+		log.String("service", "billing"),
+	))
+
+	if value, _ := dfo.tags.Get("error"); value.Err() != err {
+		t.Errorf("expected the error tag, actual: %v", value)
+	}
+	expected := map[string]string{
+		"request": "abc",
+		"user":    "42",
+		"context": "result from stringer",
+		"service": "billing",
+	}
+	for key, value := range expected {
+		if dfo.tag(key) != value {
+			t.Errorf("expected tag %s to be %q, actual: %q", key, value, dfo.tag(key))
+		}
+	}
+	if value, _ := dfo.tags.Get("user"); value.Kind() != log.KindInt64 {
+		t.Errorf("expected the key value pair to keep its type, actual: %v", value.Kind())
+	}
+}
+
+func TestLog_InfoShouldLetLaterContextItemsWin(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{Encoder: log.JsonEncoder{}, Writer: output, Tags: log.Fields{log.String("user", "tag")}})
+	first := errors.New("first")
+	second := errors.New("second")
+
+	logger.Info("message", log.With("user", "pair", first, map[string]string{"user": "map"}, second, log.With("user", "nested")))
+
+	if !strings.Contains(output.String(), `"user":"nested"`) {
+		t.Errorf("expected the last user, actual: %q", output.String())
+	}
+	if !strings.Contains(output.String(), `"error":{"message":"second"`) || strings.Contains(output.String(), "first") {
+		t.Errorf("expected the last error, actual: %q", output.String())
+	}
+}
+
+func TestLog_ChildLoggerShouldAcceptContextItems(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	parent := newContextLogger(dfo)
+
+	child, err := parent.ChildLogger("child", log.With(map[string]string{"request": "abc"}, "attempt", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	child.Info("message", log.With("attempt", 3))
+
+	if dfo.tag("request") != "abc" || dfo.tag("attempt") != "3" || dfo.tag("service") != "orders" {
+		t.Errorf("unexpected tags %v", dfo.tags)
+	}
+}
+
+func TestLog_InfoShouldReturnMissingContextValue(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	logger := newContextLogger(dfo)
+
+	err := logger.Info("message", log.With("request", "abc", "user"))
+	_, childErr := logger.ChildLogger("child", log.With("user"))
+
+	if err != log.MissingContextValue("user") || childErr != log.MissingContextValue("user") {
+		t.Errorf("expected MissingContextValue, actual: %v, %v", err, childErr)
+	}
+	if dfo.outputMessage != "" {
+		t.Errorf("expected no output, actual: %q", dfo.outputMessage)
+	}
+}
+
+func TestLog_InfoShouldReturnInvalidContextForInvalidContextItems(t *testing.T) {
+	logger := newContextLogger(new(DummyFormatOutput))
+
+	err := logger.Info("message", log.With(map[string]string{"request": "abc"}, 42))
+
+	if _, ok := err.(*log.InvalidContext); !ok {
+		t.Errorf("expected InvalidContext, actual: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"strings"
+	"time"
+)
+
+// Encoder is the append-style counterpart of Config.Formatter. A record is
+// encoded by one call to AppendHeader, one call to AppendField for every tag
+// in key order and a final call to AppendFooter. Every call must render its
+// part independently of the others so that loggers can cache the rendered
+// tags of their parents.
+type Encoder interface {
+	AppendHeader(dst []byte, t time.Time, dateFormat string, level string, message string, function string) []byte
+	AppendField(dst []byte, field Field) []byte
+	AppendFooter(dst []byte) []byte
+}
+
+// TextEncoder encodes records in the format of TextFormatter.
+type TextEncoder struct{}
+
+func (TextEncoder) AppendHeader(dst []byte, t time.Time, dateFormat string, level string, message string, function string) []byte {
+	start := len(dst)
+	dst = t.AppendFormat(dst, dateFormat)
+	dst = escapeTextTail(dst, start, textColumnSeparators)
+	dst = append(dst, '\t')
+	dst = appendTextEscaped(dst, level, textColumnSeparators)
+	dst = append(dst, '\t')
+	dst = appendTextEscaped(dst, function, textColumnSeparators)
+	dst = append(dst, '\t')
+	dst = appendTextEscaped(dst, message, textColumnSeparators)
+	return append(dst, '\t')
+}
+
+func (TextEncoder) AppendField(dst []byte, field Field) []byte {
+	if field.Key == "function" || len(field.Key) == 0 {
+		return dst
+	}
+	return appendTextField(dst, "", field)
+}
+
+func (TextEncoder) AppendFooter(dst []byte) []byte {
+	// every tag ends with a separator, the header ends with a tab
+	if len(dst) > 0 && dst[len(dst)-1] == ',' {
+		return dst[:len(dst)-1]
+	}
+	return dst
+}
+
+// JsonEncoder encodes records in the format of JsonFormatter.
+type JsonEncoder struct{}
+
+func (JsonEncoder) AppendHeader(dst []byte, t time.Time, dateFormat string, level string, message string, function string) []byte {
+	dst = append(dst, `{"time":"`...)
+	start := len(dst)
+	dst = t.AppendFormat(dst, dateFormat)
+	if needsJSONEscape(dst[start:]) {
+		formatted := string(dst[start:])
+		dst = appendJSONStringContent(dst[:start], formatted)
+	}
+	dst = append(dst, `","level":`...)
+	dst = appendJSONString(dst, level)
+	dst = append(dst, `,"message":`...)
+	return appendJSONString(dst, message)
+}
+
+func (JsonEncoder) AppendField(dst []byte, field Field) []byte {
+	dst = append(dst, ',', '"')
+	if isReservedJsonKey(field.Key) {
+		dst = append(dst, '_')
+	}
+	dst = appendJSONStringContent(dst, field.Key)
+	dst = append(dst, '"', ':')
+	return appendJSONValue(dst, field.Value)
+}
+
+func (JsonEncoder) AppendFooter(dst []byte) []byte {
+	return append(dst, '}')
+}
+
+// isReservedJsonKey reports whether a tag key has to be prefixed with an
+// underscore. Prefixing keys that already consist of underscores followed by
+// a reserved key as well keeps the renamed keys unique. Encoders render a tag
+// without seeing the other tags of the record, so these keys are prefixed
+// even if nothing collides with them: _level is written as __level.
+func isReservedJsonKey(key string) bool {
+	switch strings.TrimLeft(key, "_") {
+	case "time", "level", "message":
+		return true
+	}
+	return false
+}
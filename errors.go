@@ -0,0 +1,170 @@
+package log
+
+import (
+	"reflect"
+)
+
+const maxErrorDepth = 32
+
+// ErrorFields is implemented by errors that carry structured context. The
+// fields are logged together with the error.
+type ErrorFields interface {
+	ErrorFields() Fields
+}
+
+// errorDetails is an error taken apart for rendering: the errors it wraps,
+// found with Unwrap, Unwrap []error as created by errors.Join and Cause,
+// depth first, the first stack trace and the fields of all layers. Fields of
+// outer layers win.
+type errorDetails struct {
+	message  string
+	typeName string
+	chain    []errorLayer
+	stack    string
+	fields   Fields
+}
+
+type errorLayer struct {
+	message  string
+	typeName string
+}
+
+func describeError(err error) *errorDetails {
+	if redactedErr, ok := err.(*redactedError); ok {
+		return redactedErr.details
+	}
+	if isNilPointer(err) {
+		return &errorDetails{message: "<nil>", typeName: reflect.TypeOf(err).String()}
+	}
+	details := &errorDetails{message: err.Error(), typeName: reflect.TypeOf(err).String()}
+	details.add(err)
+	return details
+}
+
+// redactedError replaces an error whose messages or fields were redacted. It
+// is rendered from its redacted details and does not unwrap to the original
+// error, so that the original messages can not be read through it.
+type redactedError struct {
+	details *errorDetails
+}
+
+func (re *redactedError) Error() string {
+	return re.details.message
+}
+
+func (details *errorDetails) add(err error) {
+	if len(details.stack) == 0 {
+		details.stack = errorStack(err)
+	}
+	if fielder, ok := err.(ErrorFields); ok {
+		for _, field := range fielder.ErrorFields() {
+			if _, found := details.fields.Get(field.Key); !found {
+				details.fields = append(details.fields, field)
+			}
+		}
+	}
+	for _, wrapped := range unwrapError(err) {
+		if wrapped == nil || len(details.chain) >= maxErrorDepth {
+			continue
+		}
+		if isNilPointer(wrapped) {
+			details.chain = append(details.chain, errorLayer{"<nil>", reflect.TypeOf(wrapped).String()})
+			continue
+		}
+		details.chain = append(details.chain, errorLayer{wrapped.Error(), reflect.TypeOf(wrapped).String()})
+		details.add(wrapped)
+	}
+}
+
+func unwrapError(err error) []error {
+	switch wrapper := err.(type) {
+	case interface{ Unwrap() []error }:
+		return wrapper.Unwrap()
+	case interface{ Unwrap() error }:
+		return []error{wrapper.Unwrap()}
+	case interface{ Cause() error }:
+		return []error{wrapper.Cause()}
+	}
+	return nil
+}
+
+// errorStack returns the stack trace of errors with a StackTrace method that
+// returns a string or program counters, like the errors of
+// github.com/pkg/errors.
+func errorStack(err error) string {
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return ""
+	}
+	trace := method.Call(nil)[0]
+	switch {
+	case trace.Kind() == reflect.String:
+		return trace.String()
+	case trace.Kind() == reflect.Slice && trace.Type().Elem().Kind() == reflect.Uintptr:
+		pcs := make([]uintptr, trace.Len())
+		for i := range pcs {
+			pcs[i] = uintptr(trace.Index(i).Uint())
+		}
+		return formatStack(callersOf(pcs, len(pcs)))
+	}
+	return ""
+}
+
+// appendJSONError renders an error as object with its message, type, the
+// wrapped errors, stack and fields. The last three are left out when empty.
+func appendJSONError(dst []byte, err error) []byte {
+	details := describeError(err)
+	dst = append(dst, `{"message":`...)
+	dst = appendJSONString(dst, details.message)
+	dst = append(dst, `,"type":`...)
+	dst = appendJSONString(dst, details.typeName)
+	if len(details.chain) > 0 {
+		dst = append(dst, `,"chain":[`...)
+		for i, layer := range details.chain {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = append(dst, `{"message":`...)
+			dst = appendJSONString(dst, layer.message)
+			dst = append(dst, `,"type":`...)
+			dst = appendJSONString(dst, layer.typeName)
+			dst = append(dst, '}')
+		}
+		dst = append(dst, ']')
+	}
+	if len(details.stack) > 0 {
+		dst = append(dst, `,"stack":`...)
+		dst = appendJSONString(dst, details.stack)
+	}
+	if len(details.fields) > 0 {
+		dst = append(dst, `,"fields":`...)
+		dst = appendJSONValue(dst, ObjectValue(details.fields...))
+	}
+	return append(dst, '}')
+}
+
+// appendTextError renders the message of an error. Wrapped errors and fields
+// are added in brackets as the chain of type names followed by key=value
+// pairs, stack traces are left out.
+//
+//	read config: open app.yml: no such file [*fmt.wrapError > *fs.PathError > syscall.Errno path=app.yml]
+func appendTextError(dst []byte, err error) []byte {
+	details := describeError(err)
+	dst = append(dst, details.message...)
+	if len(details.chain) == 0 && len(details.fields) == 0 {
+		return dst
+	}
+	dst = append(dst, " ["...)
+	dst = append(dst, details.typeName...)
+	for _, layer := range details.chain {
+		dst = append(dst, " > "...)
+		dst = append(dst, layer.typeName...)
+	}
+	for _, field := range details.fields {
+		dst = append(dst, ' ')
+		dst = append(dst, field.Key...)
+		dst = append(dst, '=')
+		dst = appendTextValue(dst, field.Value)
+	}
+	return append(dst, ']')
+}
@@ -0,0 +1,189 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type frame uintptr
+
+type stackTrace []frame
+
+// stackError carries a stack trace like the errors of github.com/pkg/errors.
+type stackError struct {
+	message string
+	stack   stackTrace
+}
+
+func newStackError(message string) *stackError {
+	pcs := make([]uintptr, 8)
+	count := runtime.Callers(2, pcs)
+	err := &stackError{message: message}
+	for _, pc := range pcs[:count] {
+		err.stack = append(err.stack, frame(pc))
+	}
+	return err
+}
+
+func (se *stackError) Error() string {
+	return se.message
+}
+
+func (se *stackError) StackTrace() stackTrace {
+	return se.stack
+}
+
+type fieldsError struct {
+	err    error
+	fields Fields
+}
+
+func (fe fieldsError) Error() string {
+	return fe.err.Error()
+}
+
+func (fe fieldsError) Unwrap() error {
+	return fe.err
+}
+
+func (fe fieldsError) ErrorFields() Fields {
+	return fe.fields
+}
+
+// joinedError is what errors.Join returns.
+type joinedError []error
+
+func (je joinedError) Error() string {
+	messages := make([]string, len(je))
+	for i, err := range je {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (je joinedError) Unwrap() []error {
+	return je
+}
+
+type jsonError struct {
+	Message string
+	Type    string
+	Chain   []struct {
+		Message string
+		Type    string
+	}
+	Stack  string
+	Fields map[string]interface{}
+}
+
+func decodeJsonError(t *testing.T, err error) jsonError {
+	rendered := appendJSONValue(nil, ErrorValue(err))
+	decoded := jsonError{}
+	if unmarshalErr := json.Unmarshal(rendered, &decoded); unmarshalErr != nil {
+		t.Fatalf("invalid json %q: %s", rendered, unmarshalErr)
+	}
+	return decoded
+}
+
+func TestJsonFormatterRendersTheErrorChain(t *testing.T) {
+	inner := fieldsError{errors.New("no such file"), Fields{String("path", "app.yml"), Int("attempt", 1)}}
+	outer := fieldsError{fmt.Errorf("read config: %w", inner), Fields{Int("attempt", 2)}}
+
+	decoded := decodeJsonError(t, outer)
+
+	if decoded.Message != "read config: no such file" || decoded.Type != "log.fieldsError" {
+		t.Errorf("unexpected error %+v", decoded)
+	}
+	expectedTypes := []string{"*fmt.wrapError", "log.fieldsError", "*errors.errorString"}
+	if len(decoded.Chain) != len(expectedTypes) {
+		t.Fatalf("expected chain of %v, actual: %+v", expectedTypes, decoded.Chain)
+	}
+	for i, expectedType := range expectedTypes {
+		if decoded.Chain[i].Type != expectedType {
+			t.Errorf("expected layer %d to be %s, actual: %s", i, expectedType, decoded.Chain[i].Type)
+		}
+	}
+	if decoded.Chain[2].Message != "no such file" {
+		t.Errorf("expected the message of the innermost error, actual: %q", decoded.Chain[2].Message)
+	}
+	if decoded.Fields["path"] != "app.yml" || decoded.Fields["attempt"] != 2.0 {
+		t.Errorf("expected the fields of all layers with outer ones winning, actual: %v", decoded.Fields)
+	}
+	if decoded.Stack != "" {
+		t.Errorf("expected no stack, actual: %q", decoded.Stack)
+	}
+}
+
+func TestJsonFormatterRendersJoinedErrorsDepthFirst(t *testing.T) {
+	first := fmt.Errorf("first: %w", errors.New("cause"))
+	second := errors.New("second")
+
+	decoded := decodeJsonError(t, joinedError{first, second})
+
+	messages := []string{}
+	for _, layer := range decoded.Chain {
+		messages = append(messages, layer.Message)
+	}
+	if strings.Join(messages, "|") != "first: cause|cause|second" {
+		t.Errorf("unexpected chain %v", messages)
+	}
+}
+
+func TestJsonFormatterRendersTheStackTraceOfAnError(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", newStackError("failed"))
+
+	decoded := decodeJsonError(t, err)
+
+	if !strings.HasPrefix(decoded.Stack, "github.com/flowpl/log.TestJsonFormatterRendersTheStackTraceOfAnError\n\t") {
+		t.Errorf("expected the stack of the wrapped error, actual: %q", decoded.Stack)
+	}
+}
+
+func TestTextFormatterRendersErrorsCompactly(t *testing.T) {
+	inner := fieldsError{newStackError("no such file"), Fields{String("path", "app.yml")}}
+	err := fmt.Errorf("read config: %w", inner)
+
+	resultString := TextFormatter(testTime, "L", "m", Fields{Err("error", err), Err("plain", errors.New("failed"))}, "2006")
+	record, parseErr := ParseText(resultString)
+
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	expected := Fields{
+		String("error", "read config: no such file [*fmt.wrapError > log.fieldsError > *log.stackError path=app.yml]"),
+		String("plain", "failed"),
+	}
+	if !record.Tags.Equal(expected) {
+		t.Errorf("expected %v, actual: %v", expected, record.Tags)
+	}
+}
+
+func TestConsoleFormatterShowsTheStackOfErrorsBelowTheRecord(t *testing.T) {
+	result := formatConsole(ConsoleFormatter{}, "INFO", "failed", Fields{Err("error", newStackError("boom"))}, "15:04")
+
+	lines := strings.Split(result, "\n")
+	if !strings.HasSuffix(lines[0], " error=boom") || len(lines) < 3 {
+		t.Fatalf("unexpected record %q", result)
+	}
+	if lines[1] != "    error.stack:" || lines[2] != "        github.com/flowpl/log.TestConsoleFormatterShowsTheStackOfErrorsBelowTheRecord" {
+		t.Errorf("expected the stack below the record, actual: %q", lines[1:3])
+	}
+}
+
+func TestFormattersRenderNilErrorPointers(t *testing.T) {
+	var nilErr *stackError
+	wrapped := fmt.Errorf("failed: %w", nilErr)
+
+	decoded := decodeJsonError(t, wrapped)
+
+	if decoded.Message != "failed: <nil>" || len(decoded.Chain) != 1 || decoded.Chain[0].Message != "<nil>" || decoded.Chain[0].Type != "*log.stackError" {
+		t.Errorf("unexpected error %+v", decoded)
+	}
+	if text := string(appendTextValue(nil, ErrorValue(nilErr))); text != "<nil>" {
+		t.Errorf("expected <nil>, actual: %q", text)
+	}
+}
@@ -4,7 +4,16 @@ import (
 	"github.com/flowpl/log"
 )
 
-type FakeLogger struct {}
-func (fl *FakeLogger) Info(message string, context interface{}) error { return nil }
-func (fl *FakeLogger) Debug(message string, context interface{}) error { return nil }
-func (fl *FakeLogger) ChildLogger(function string, context interface{}) (log.Logger, error) { return fl, nil }
+type FakeLogger struct{}
+
+func (fl *FakeLogger) Info(message string, context interface{}) error           { return nil }
+func (fl *FakeLogger) Debug(message string, context interface{}) error          { return nil }
+func (fl *FakeLogger) DebugFunc(messageFunc func() (string, interface{})) error { return nil }
+func (fl *FakeLogger) Trace(message string, context interface{}) error          { return nil }
+func (fl *FakeLogger) Warn(message string, context interface{}) error           { return nil }
+func (fl *FakeLogger) Error(message string, context interface{}) error          { return nil }
+func (fl *FakeLogger) Fatal(message string, context interface{}) error          { return nil }
+func (fl *FakeLogger) Enabled(level string) bool                                { return false }
+func (fl *FakeLogger) ChildLogger(function string, context interface{}) (log.Logger, error) {
+	return fl, nil
+}
@@ -0,0 +1,415 @@
+package log
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type Kind uint8
+
+const (
+	KindString Kind = iota
+	KindInt64
+	KindFloat64
+	KindBool
+	KindTime
+	KindDuration
+	KindError
+	KindObject
+	KindArray
+)
+
+var kindNames = [...]string{"string", "int64", "float64", "bool", "time", "duration", "error", "object", "array"}
+
+func (kind Kind) String() string {
+	if int(kind) < len(kindNames) {
+		return kindNames[kind]
+	}
+	return "Kind(" + strconv.Itoa(int(kind)) + ")"
+}
+
+// Value is a typed tag value. Scalars are stored inline so that building
+// fields from strings, numbers and booleans does not allocate.
+type Value struct {
+	kind Kind
+	num  uint64
+	str  string
+	any  interface{}
+}
+
+func StringValue(value string) Value {
+	return Value{kind: KindString, str: value}
+}
+
+func Int64Value(value int64) Value {
+	return Value{kind: KindInt64, num: uint64(value)}
+}
+
+func Float64Value(value float64) Value {
+	return Value{kind: KindFloat64, num: math.Float64bits(value)}
+}
+
+func BoolValue(value bool) Value {
+	if value {
+		return Value{kind: KindBool, num: 1}
+	}
+	return Value{kind: KindBool}
+}
+
+func TimeValue(value time.Time) Value {
+	return Value{kind: KindTime, any: value}
+}
+
+func DurationValue(value time.Duration) Value {
+	return Value{kind: KindDuration, num: uint64(value)}
+}
+
+func ErrorValue(value error) Value {
+	return Value{kind: KindError, any: value}
+}
+
+func ObjectValue(fields ...Field) Value {
+	return Value{kind: KindObject, any: Fields(fields)}
+}
+
+func ArrayValue(values ...Value) Value {
+	return Value{kind: KindArray, any: values}
+}
+
+// LogMarshaler is implemented by types that log themselves as fields. Passed
+// as context, the fields become tags of the record, as value they are rendered
+// as nested object.
+type LogMarshaler interface {
+	MarshalLog() Fields
+}
+
+// AnyValue converts an arbitrary go value into the closest typed Value.
+// Values without a native representation are rendered with fmt.
+func AnyValue(value interface{}) Value {
+	switch value.(type) {
+	case LogMarshaler, error, fmt.Stringer:
+		if isNilPointer(value) {
+			return StringValue("<nil>")
+		}
+	}
+	switch v := value.(type) {
+	case nil:
+		return StringValue("<nil>")
+	case Value:
+		return v
+	case Field:
+		return ObjectValue(v)
+	case Fields:
+		return ObjectValue(v...)
+	case LogMarshaler:
+		return ObjectValue(v.MarshalLog()...)
+	case []Value:
+		return ArrayValue(v...)
+	case string:
+		return StringValue(v)
+	case bool:
+		return BoolValue(v)
+	case int:
+		return Int64Value(int64(v))
+	case int8:
+		return Int64Value(int64(v))
+	case int16:
+		return Int64Value(int64(v))
+	case int32:
+		return Int64Value(int64(v))
+	case int64:
+		return Int64Value(v)
+	case uint:
+		return uintValue(uint64(v))
+	case uint8:
+		return Int64Value(int64(v))
+	case uint16:
+		return Int64Value(int64(v))
+	case uint32:
+		return Int64Value(int64(v))
+	case uint64:
+		return uintValue(v)
+	case float32:
+		return Float64Value(float64(v))
+	case float64:
+		return Float64Value(v)
+	case time.Time:
+		return TimeValue(v)
+	case time.Duration:
+		return DurationValue(v)
+	case error:
+		return ErrorValue(v)
+	case fmt.Stringer:
+		return StringValue(v.String())
+	}
+	return reflectedValue(reflect.ValueOf(value))
+}
+
+// isNilPointer reports whether value is a typed nil pointer, whose methods
+// would dereference nil.
+func isNilPointer(value interface{}) bool {
+	reflected := reflect.ValueOf(value)
+	return reflected.Kind() == reflect.Ptr && reflected.IsNil()
+}
+
+func uintValue(value uint64) Value {
+	if value > math.MaxInt64 {
+		return StringValue(strconv.FormatUint(value, 10))
+	}
+	return Int64Value(int64(value))
+}
+
+func reflectedValue(value reflect.Value) Value {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return StringValue("<nil>")
+		}
+		return AnyValue(value.Elem().Interface())
+	case reflect.String:
+		return StringValue(value.String())
+	case reflect.Bool:
+		return BoolValue(value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Int64Value(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintValue(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return Float64Value(value.Float())
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Uint8 {
+			return StringValue(string(value.Bytes()))
+		}
+		values := make([]Value, value.Len())
+		for i := range values {
+			values[i] = AnyValue(value.Index(i).Interface())
+		}
+		return ArrayValue(values...)
+	case reflect.Map:
+		return ObjectValue(mapFields(value)...)
+	}
+	return StringValue(fmt.Sprintf("%+v", value.Interface()))
+}
+
+// mapFields returns the entries of a map as fields sorted by key, keeping the
+// types of the values.
+func mapFields(value reflect.Value) Fields {
+	fields := make(Fields, 0, value.Len())
+	iterator := value.MapRange()
+	for iterator.Next() {
+		fields = append(fields, Any(mapKey(iterator.Key()), iterator.Value().Interface()))
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	return fields
+}
+
+// mapKey renders a map key with its MarshalText method or like fmt.Sprint.
+func mapKey(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	if marshaler, ok := key.Interface().(encoding.TextMarshaler); ok {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	return fmt.Sprint(key.Interface())
+}
+
+func (value Value) Kind() Kind {
+	return value.kind
+}
+
+func (value Value) Int64() int64 {
+	return int64(value.num)
+}
+
+func (value Value) Float64() float64 {
+	return math.Float64frombits(value.num)
+}
+
+func (value Value) Bool() bool {
+	return value.num == 1
+}
+
+func (value Value) Time() time.Time {
+	t, _ := value.any.(time.Time)
+	return t
+}
+
+func (value Value) Duration() time.Duration {
+	return time.Duration(value.num)
+}
+
+func (value Value) Err() error {
+	err, _ := value.any.(error)
+	return err
+}
+
+func (value Value) Object() Fields {
+	fields, _ := value.any.(Fields)
+	return fields
+}
+
+func (value Value) Array() []Value {
+	values, _ := value.any.([]Value)
+	return values
+}
+
+// Interface returns the go value held by value.
+func (value Value) Interface() interface{} {
+	switch value.kind {
+	case KindString:
+		return value.str
+	case KindInt64:
+		return value.Int64()
+	case KindFloat64:
+		return value.Float64()
+	case KindBool:
+		return value.Bool()
+	case KindDuration:
+		return value.Duration()
+	}
+	return value.any
+}
+
+// String renders value the way TextFormatter does.
+func (value Value) String() string {
+	if value.kind == KindString {
+		return value.str
+	}
+	return string(appendTextValue(nil, value))
+}
+
+func (value Value) Equal(other Value) bool {
+	if value.kind != other.kind {
+		return false
+	}
+	switch value.kind {
+	case KindString:
+		return value.str == other.str
+	case KindTime:
+		return value.Time().Equal(other.Time())
+	case KindError:
+		return value.Err() == other.Err()
+	case KindObject:
+		return value.Object().Equal(other.Object())
+	case KindArray:
+		values, others := value.Array(), other.Array()
+		if len(values) != len(others) {
+			return false
+		}
+		for i := range values {
+			if !values[i].Equal(others[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return value.num == other.num
+}
+
+type Field struct {
+	Key   string
+	Value Value
+}
+
+func String(key string, value string) Field {
+	return Field{key, StringValue(value)}
+}
+
+func Int(key string, value int) Field {
+	return Field{key, Int64Value(int64(value))}
+}
+
+func Int64(key string, value int64) Field {
+	return Field{key, Int64Value(value)}
+}
+
+func Float64(key string, value float64) Field {
+	return Field{key, Float64Value(value)}
+}
+
+func Bool(key string, value bool) Field {
+	return Field{key, BoolValue(value)}
+}
+
+func Time(key string, value time.Time) Field {
+	return Field{key, TimeValue(value)}
+}
+
+func Duration(key string, value time.Duration) Field {
+	return Field{key, DurationValue(value)}
+}
+
+func Err(key string, value error) Field {
+	return Field{key, ErrorValue(value)}
+}
+
+func Object(key string, fields ...Field) Field {
+	return Field{key, ObjectValue(fields...)}
+}
+
+func Array(key string, values ...Value) Field {
+	return Field{key, ArrayValue(values...)}
+}
+
+func Any(key string, value interface{}) Field {
+	return Field{key, AnyValue(value)}
+}
+
+// Fields is an ordered list of tags. Keys are unique within the fields of a
+// logger; when fields are merged, later values replace earlier ones.
+type Fields []Field
+
+func (fields Fields) Get(key string) (Value, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == key {
+			return fields[i].Value, true
+		}
+	}
+	return Value{}, false
+}
+
+func (fields Fields) Equal(other Fields) bool {
+	if len(fields) != len(other) {
+		return false
+	}
+	for i := range fields {
+		if fields[i].Key != other[i].Key || !fields[i].Value.Equal(other[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (fields Fields) set(field Field) Fields {
+	for i := range fields {
+		if fields[i].Key == field.Key {
+			fields[i] = field
+			return fields
+		}
+	}
+	return append(fields, field)
+}
+
+// sorted returns a copy of fields sorted by key, keeping only the last value
+// of duplicate keys.
+func (fields Fields) sorted() Fields {
+	output := make(Fields, len(fields))
+	copy(output, fields)
+	sort.SliceStable(output, func(i, j int) bool { return output[i].Key < output[j].Key })
+	unique := output[:0]
+	for i := range output {
+		if i+1 < len(output) && output[i+1].Key == output[i].Key {
+			continue
+		}
+		unique = append(unique, output[i])
+	}
+	return unique
+}
@@ -0,0 +1,147 @@
+package log
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type namedInt int
+
+type stringerValue struct{}
+
+func (sv stringerValue) String() string {
+	return "from stringer"
+}
+
+func TestAnyValueConvertsToNativeKinds(t *testing.T) {
+	timestamp := time.Unix(0, 0)
+	err := errors.New("failed")
+	testCases := []struct {
+		input    interface{}
+		expected Value
+	}{
+		{"value", StringValue("value")},
+		{42, Int64Value(42)},
+		{uint8(7), Int64Value(7)},
+		{namedInt(3), Int64Value(3)},
+		{float32(0.5), Float64Value(0.5)},
+		{true, BoolValue(true)},
+		{timestamp, TimeValue(timestamp)},
+		{time.Second, DurationValue(time.Second)},
+		{err, ErrorValue(err)},
+		{stringerValue{}, StringValue("from stringer")},
+		{[]int{1, 2}, ArrayValue(Int64Value(1), Int64Value(2))},
+		{[]byte("bytes"), StringValue("bytes")},
+		{Fields{Int("a", 1)}, ObjectValue(Int("a", 1))},
+		{nil, StringValue("<nil>")},
+	}
+
+	for _, testCase := range testCases {
+		result := AnyValue(testCase.input)
+		if !result.Equal(testCase.expected) {
+			t.Errorf("AnyValue(%#v): expected %s %q, actual: %s %q", testCase.input, testCase.expected.Kind(), testCase.expected, result.Kind(), result)
+		}
+	}
+}
+
+func TestAnyValueKeepsLargeUnsignedIntegersExact(t *testing.T) {
+	result := AnyValue(uint64(1<<64 - 1))
+
+	if result.Kind() != KindString || result.String() != "18446744073709551615" {
+		t.Errorf("expected string 18446744073709551615, actual: %s %q", result.Kind(), result)
+	}
+}
+
+func TestFieldsGetReturnsTheLastValueForAKey(t *testing.T) {
+	fields := Fields{String("key", "first"), Int("other", 1), String("key", "second")}
+	value, ok := fields.Get("key")
+
+	if !ok || value.String() != "second" {
+		t.Errorf("expected \"second\", actual: %q", value)
+	}
+
+	if _, ok := fields.Get("missing"); ok {
+		t.Error("expected missing key not to be found")
+	}
+}
+
+func TestFieldsSortedOrdersByKeyAndRemovesDuplicates(t *testing.T) {
+	fields := Fields{String("b", "1"), String("a", "2"), String("b", "3")}
+	result := fields.sorted()
+	expected := Fields{String("a", "2"), String("b", "3")}
+
+	if !result.Equal(expected) {
+		t.Errorf("expected %v, actual: %v", expected, result)
+	}
+}
+
+type textKey struct {
+	region string
+	id     int
+}
+
+func (tk textKey) MarshalText() ([]byte, error) {
+	return []byte(tk.region + "-" + strconv.Itoa(tk.id)), nil
+}
+
+func TestAnyValueRendersMapsAsObjectsSortedByKey(t *testing.T) {
+	cases := []struct {
+		context  interface{}
+		expected Fields
+	}{
+		{map[string]int{"b": 2, "a": 1}, Fields{Int("a", 1), Int("b", 2)}},
+		{map[int]string{10: "ten", 2: "two"}, Fields{String("10", "ten"), String("2", "two")}},
+		{map[bool]float64{true: 1.5}, Fields{Float64("true", 1.5)}},
+		{map[namedInt]time.Duration{3: time.Second}, Fields{Duration("3", time.Second)}},
+		{map[stringerValue]bool{{}: true}, Fields{Bool("from stringer", true)}},
+		{map[textKey]int64{{"eu", 1}: 7}, Fields{Int64("eu-1", 7)}},
+		{map[string]interface{}{"user": map[string]interface{}{"id": 42, "roles": []string{"admin"}}}, Fields{
+			Object("user", Int("id", 42), Array("roles", StringValue("admin"))),
+		}},
+		{map[string]map[int]bool{"flags": {1: true}}, Fields{Object("flags", Bool("1", true))}},
+		{map[string]string(nil), Fields{}},
+	}
+	for _, c := range cases {
+		value := AnyValue(c.context)
+		if value.Kind() != KindObject || !value.Object().Equal(c.expected) {
+			t.Errorf("%#v: expected %v, actual: %v", c.context, c.expected, value)
+		}
+	}
+}
+
+func TestMergeTagsKeepsTheKindsOfMapKeysAndValues(t *testing.T) {
+	tags, err := mergeTags(Fields{String("a", "tag")}, map[int]interface{}{1: 1.5, 2: map[string]int{"x": 1}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Fields{String("a", "tag"), Float64("1", 1.5), Object("2", Int("x", 1))}
+	if !tags.Equal(expected) {
+		t.Errorf("expected %v, actual: %v", expected, tags)
+	}
+}
+
+func TestAnyValueRendersNilPointersWithMethodsAsNil(t *testing.T) {
+	var marshaler *nilMarshaler
+	value := AnyValue(map[string]interface{}{"url": (*url.URL)(nil), "error": (*stackError)(nil), "marshaler": marshaler})
+
+	expected := ObjectValue(String("error", "<nil>"), String("marshaler", "<nil>"), String("url", "<nil>"))
+	if !value.Equal(expected) {
+		t.Errorf("expected %v, actual: %v", expected, value)
+	}
+	value = AnyValue(map[string]*url.URL{"url": nil})
+	if expected := ObjectValue(String("url", "<nil>")); !value.Equal(expected) {
+		t.Errorf("expected %v, actual: %v", expected, value)
+	}
+}
+
+type nilMarshaler struct {
+	fields Fields
+}
+
+func (nm *nilMarshaler) MarshalLog() Fields {
+	return nm.fields
+}
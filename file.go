@@ -0,0 +1,304 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "20060102T150405.000000"
+
+type FileConfig struct {
+	Filename string
+	// MaxSize rotates the file before a write would make it larger than
+	// MaxSize bytes. Zero disables size based rotation.
+	MaxSize int64
+	// RotateEvery rotates the file once it has been open for the duration.
+	// Zero disables time based rotation.
+	RotateEvery time.Duration
+	// MaxBackups is the number of rotated files to keep. Zero keeps all.
+	MaxBackups int
+	// Compress gzips rotated files in the background.
+	Compress bool
+	// FileMode defaults to 0644.
+	FileMode os.FileMode
+}
+
+// FileOutput is an io.Writer that appends records to a file and rotates it by
+// size and time. Rotated files are renamed to <name>-<timestamp><ext>, see
+// backupName for rotations within the same microsecond. It is safe for
+// concurrent use and writes every record atomically.
+type FileOutput struct {
+	config FileConfig
+	mutex  sync.Mutex
+	// file is nil after Close and after opening the file failed, which is
+	// retried by the next Write, Rotate or Reopen unless closed is set.
+	file     *os.File
+	closed   bool
+	size     int64
+	openedAt time.Time
+	now      func() time.Time
+
+	// compression and removal of backups run in the background, one at a time
+	maintenance sync.Mutex
+	pending     sync.WaitGroup
+}
+
+func NewFileOutput(config FileConfig) (*FileOutput, error) {
+	if config.FileMode == 0 {
+		config.FileMode = 0644
+	}
+	fileOutput := &FileOutput{config: config, now: time.Now}
+	if err := fileOutput.open(); err != nil {
+		return nil, err
+	}
+	return fileOutput, nil
+}
+
+func (fo *FileOutput) Write(record []byte) (int, error) {
+	fo.mutex.Lock()
+	defer fo.mutex.Unlock()
+	if fo.closed {
+		return 0, os.ErrClosed
+	}
+	if fo.file == nil {
+		if err := fo.open(); err != nil {
+			return 0, err
+		}
+	} else if fo.shouldRotate(int64(len(record))) {
+		if err := fo.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	written, err := fo.file.Write(record)
+	fo.size += int64(written)
+	return written, err
+}
+
+// Rotate renames the current file to a backup and continues in a new file.
+func (fo *FileOutput) Rotate() error {
+	fo.mutex.Lock()
+	defer fo.mutex.Unlock()
+	if fo.closed {
+		return os.ErrClosed
+	}
+	if fo.file == nil {
+		return fo.open()
+	}
+	return fo.rotate()
+}
+
+// Reopen closes and reopens the file, e.g. after logrotate moved it. If the
+// file can not be opened, the next Write or Reopen tries again.
+func (fo *FileOutput) Reopen() error {
+	fo.mutex.Lock()
+	defer fo.mutex.Unlock()
+	if fo.closed {
+		return os.ErrClosed
+	}
+	if fo.file != nil {
+		err := fo.file.Close()
+		fo.file = nil
+		if err != nil {
+			return err
+		}
+	}
+	return fo.open()
+}
+
+// ReopenOnSignal reopens the file whenever one of the signals, typically
+// syscall.SIGHUP, is received. Reopen errors are passed to errorHandler, which
+// may be nil. The returned function stops listening for the signals.
+func (fo *FileOutput) ReopenOnSignal(errorHandler func(error), signals ...os.Signal) (stop func()) {
+	received := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(received, signals...)
+	go func() {
+		for {
+			select {
+			case <-received:
+				if err := fo.Reopen(); err != nil && errorHandler != nil {
+					errorHandler(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(received)
+			close(done)
+		})
+	}
+}
+
+// Close closes the file and waits for background compression to finish.
+func (fo *FileOutput) Close() error {
+	fo.mutex.Lock()
+	var err error
+	if fo.file != nil {
+		err = fo.file.Close()
+		fo.file = nil
+	}
+	fo.closed = true
+	fo.mutex.Unlock()
+	fo.pending.Wait()
+	return err
+}
+
+func (fo *FileOutput) shouldRotate(recordSize int64) bool {
+	if fo.config.MaxSize > 0 && fo.size > 0 && fo.size+recordSize > fo.config.MaxSize {
+		return true
+	}
+	return fo.config.RotateEvery > 0 && fo.now().Sub(fo.openedAt) >= fo.config.RotateEvery
+}
+
+func (fo *FileOutput) open() error {
+	if err := os.MkdirAll(filepath.Dir(fo.config.Filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(fo.config.Filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, fo.config.FileMode)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	fo.file = file
+	fo.size = info.Size()
+	fo.openedAt = fo.now()
+	return nil
+}
+
+func (fo *FileOutput) rotate() error {
+	err := fo.file.Close()
+	fo.file = nil
+	if err != nil {
+		return err
+	}
+	backup := fo.backupName(fo.now())
+	if err := os.Rename(fo.config.Filename, backup); err != nil && !os.IsNotExist(err) {
+		// keep writing to the current file
+		fo.open()
+		return err
+	}
+	if err := fo.open(); err != nil {
+		return err
+	}
+
+	fo.pending.Add(1)
+	go func() {
+		defer fo.pending.Done()
+		fo.maintenance.Lock()
+		defer fo.maintenance.Unlock()
+		if fo.config.Compress {
+			compressFile(backup)
+		}
+		fo.removeOldBackups()
+	}()
+	return nil
+}
+
+// backupName returns <name>-<timestamp><ext>, or <name>-<timestamp>_<n><ext>
+// with a zero padded counter if there is a backup with the timestamp already.
+// The underscore sorts after the dot of the extension, so that backups sort
+// oldest first.
+func (fo *FileOutput) backupName(t time.Time) string {
+	extension := filepath.Ext(fo.config.Filename)
+	prefix := strings.TrimSuffix(fo.config.Filename, extension) + "-" + t.Format(backupTimeFormat)
+	name := prefix + extension
+	for i := 1; fileExists(name) || fileExists(name+".gz"); i++ {
+		name = prefix + "_" + padCounter(i) + extension
+	}
+	return name
+}
+
+func padCounter(i int) string {
+	counter := strconv.Itoa(i)
+	for len(counter) < 3 {
+		counter = "0" + counter
+	}
+	return counter
+}
+
+// backups returns the rotated files, oldest first.
+func (fo *FileOutput) backups() ([]string, error) {
+	directory := filepath.Dir(fo.config.Filename)
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Base(fo.config.Filename)
+	prefix := strings.TrimSuffix(base, filepath.Ext(base)) + "-"
+	backups := []string{}
+	for _, entry := range entries {
+		timestamp := strings.TrimPrefix(entry.Name(), prefix)
+		if entry.IsDir() || timestamp == entry.Name() || len(timestamp) < len(backupTimeFormat) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, timestamp[:len(backupTimeFormat)]); err == nil {
+			backups = append(backups, filepath.Join(directory, entry.Name()))
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+func (fo *FileOutput) removeOldBackups() {
+	if fo.config.MaxBackups <= 0 {
+		return
+	}
+	backups, err := fo.backups()
+	if err != nil {
+		return
+	}
+	for len(backups) > fo.config.MaxBackups {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+}
+
+func compressFile(name string) error {
+	source, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+	target, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(target)
+	_, err = io.Copy(writer, source)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+	return os.Remove(name)
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
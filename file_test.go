@@ -0,0 +1,253 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func readLines(t *testing.T, name string) []string {
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("reading %s failed with error: %s", name, err.Error())
+	}
+	if strings.HasSuffix(name, ".gz") {
+		reader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("gzip.NewReader failed with error: %s", err.Error())
+		}
+		if content, err = ioutil.ReadAll(reader); err != nil {
+			t.Fatalf("reading %s failed with error: %s", name, err.Error())
+		}
+	}
+	return strings.Fields(string(content))
+}
+
+func TestFileOutputShouldRotateBySize(t *testing.T) {
+	directory := t.TempDir()
+	output, err := NewFileOutput(FileConfig{Filename: filepath.Join(directory, "app.log"), MaxSize: 20})
+	if err != nil {
+		t.Fatalf("NewFileOutput failed with error: %s", err.Error())
+	}
+	for _, record := range []string{"record-1\n", "record-2\n", "record-3\n"} {
+		output.Write([]byte(record))
+	}
+	output.Close()
+
+	backups, _ := output.backups()
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 backup, actual: %v", backups)
+	}
+
+	if lines := readLines(t, backups[0]); strings.Join(lines, ",") != "record-1,record-2" {
+		t.Errorf("expected backup to contain record-1,record-2, actual: %v", lines)
+	}
+
+	if lines := readLines(t, filepath.Join(directory, "app.log")); strings.Join(lines, ",") != "record-3" {
+		t.Errorf("expected current file to contain record-3, actual: %v", lines)
+	}
+}
+
+func TestFileOutputShouldRotateByTime(t *testing.T) {
+	directory := t.TempDir()
+	now := time.Date(2016, 7, 14, 13, 0, 0, 0, time.UTC)
+	output, _ := NewFileOutput(FileConfig{Filename: filepath.Join(directory, "app.log"), RotateEvery: time.Hour})
+	output.now = func() time.Time { return now }
+	output.openedAt = now
+
+	output.Write([]byte("before\n"))
+	now = now.Add(time.Hour)
+	output.Write([]byte("after\n"))
+	output.Close()
+
+	backups, _ := output.backups()
+	expectedBackup := filepath.Join(directory, "app-20160714T140000.000000.log")
+	if len(backups) != 1 || backups[0] != expectedBackup {
+		t.Fatalf("expected backup %s, actual: %v", expectedBackup, backups)
+	}
+
+	if lines := readLines(t, filepath.Join(directory, "app.log")); strings.Join(lines, ",") != "after" {
+		t.Errorf("expected current file to contain after, actual: %v", lines)
+	}
+}
+
+func TestFileOutputShouldKeepMaxBackupsAndCompressThem(t *testing.T) {
+	directory := t.TempDir()
+	now := time.Date(2016, 7, 14, 13, 0, 0, 0, time.UTC)
+	output, _ := NewFileOutput(FileConfig{Filename: filepath.Join(directory, "app.log"), MaxBackups: 2, Compress: true})
+	output.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		output.Write([]byte("record\n"))
+		now = now.Add(time.Minute)
+		if err := output.Rotate(); err != nil {
+			t.Fatalf("Rotate failed with error: %s", err.Error())
+		}
+	}
+	output.Close()
+
+	backups, _ := output.backups()
+	expected := []string{
+		filepath.Join(directory, "app-20160714T130300.000000.log.gz"),
+		filepath.Join(directory, "app-20160714T130400.000000.log.gz"),
+	}
+	if strings.Join(backups, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected backups %v, actual: %v", expected, backups)
+	}
+
+	if lines := readLines(t, backups[0]); strings.Join(lines, ",") != "record" {
+		t.Errorf("expected compressed backup to contain record, actual: %v", lines)
+	}
+}
+
+func TestFileOutputShouldRemoveTheOldestOfBackupsWithTheSameTimestamp(t *testing.T) {
+	directory := t.TempDir()
+	now := time.Date(2016, 7, 14, 13, 0, 0, 0, time.UTC)
+	output, _ := NewFileOutput(FileConfig{Filename: filepath.Join(directory, "app.log"), MaxBackups: 2})
+	output.now = func() time.Time { return now }
+
+	for _, record := range []string{"first", "second", "third"} {
+		output.Write([]byte(record + "\n"))
+		if err := output.Rotate(); err != nil {
+			t.Fatalf("Rotate failed with error: %s", err.Error())
+		}
+	}
+	output.Close()
+
+	backups, _ := output.backups()
+	expected := []string{
+		filepath.Join(directory, "app-20160714T130000.000000_001.log"),
+		filepath.Join(directory, "app-20160714T130000.000000_002.log"),
+	}
+	if strings.Join(backups, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected backups %v, actual: %v", expected, backups)
+	}
+	if lines := readLines(t, backups[1]); strings.Join(lines, ",") != "third" {
+		t.Errorf("expected the newest backup to be kept, actual: %v", lines)
+	}
+}
+
+func TestFileOutputShouldReopenAMovedFile(t *testing.T) {
+	directory := t.TempDir()
+	filename := filepath.Join(directory, "app.log")
+	output, _ := NewFileOutput(FileConfig{Filename: filename})
+	defer output.Close()
+
+	output.Write([]byte("before\n"))
+	os.Rename(filename, filename+".1")
+	if err := output.Reopen(); err != nil {
+		t.Fatalf("Reopen failed with error: %s", err.Error())
+	}
+	output.Write([]byte("after\n"))
+
+	if lines := readLines(t, filename+".1"); strings.Join(lines, ",") != "before" {
+		t.Errorf("expected moved file to contain before, actual: %v", lines)
+	}
+
+	if lines := readLines(t, filename); strings.Join(lines, ",") != "after" {
+		t.Errorf("expected reopened file to contain after, actual: %v", lines)
+	}
+}
+
+func TestFileOutputShouldRetryOpeningAfterReopenFailed(t *testing.T) {
+	directory := t.TempDir()
+	filename := filepath.Join(directory, "app.log")
+	output, _ := NewFileOutput(FileConfig{Filename: filename})
+	defer output.Close()
+
+	os.Rename(filename, filename+".1")
+	os.Mkdir(filename, 0755) // opening a directory for writing fails
+	if err := output.Reopen(); err == nil {
+		t.Fatal("expected Reopen to fail")
+	}
+	if _, err := output.Write([]byte("lost\n")); err == nil {
+		t.Error("expected Write to fail while the file can not be opened")
+	}
+	os.Remove(filename)
+	if _, err := output.Write([]byte("first\n")); err != nil {
+		t.Fatalf("Write failed with error: %s", err.Error())
+	}
+	if err := output.Reopen(); err != nil {
+		t.Fatalf("Reopen failed with error: %s", err.Error())
+	}
+	output.Write([]byte("second\n"))
+
+	if lines := readLines(t, filename); strings.Join(lines, ",") != "first,second" {
+		t.Errorf("expected the reopened file to contain first,second, actual: %v", lines)
+	}
+}
+
+func TestFileOutputShouldReopenOnSignal(t *testing.T) {
+	directory := t.TempDir()
+	filename := filepath.Join(directory, "app.log")
+	output, _ := NewFileOutput(FileConfig{Filename: filename})
+	defer output.Close()
+	stop := output.ReopenOnSignal(nil, os.Interrupt)
+	defer stop()
+
+	os.Rename(filename, filename+".1")
+	process, _ := os.FindProcess(os.Getpid())
+	if err := process.Signal(os.Interrupt); err != nil {
+		t.Skipf("sending signals is not supported: %s", err.Error())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !fileExists(filename) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !fileExists(filename) {
+		t.Error("expected the file to be reopened after the signal")
+	}
+}
+
+func TestFileOutputShouldReturnErrClosedAfterClose(t *testing.T) {
+	output, _ := NewFileOutput(FileConfig{Filename: filepath.Join(t.TempDir(), "app.log")})
+	output.Close()
+
+	if _, err := output.Write([]byte("record\n")); err != os.ErrClosed {
+		t.Errorf("expected os.ErrClosed, actual: %v", err)
+	}
+}
+
+func TestFileOutputShouldNotLoseRecordsOfConcurrentLoggers(t *testing.T) {
+	const goroutines, records = 8, 250
+	directory := t.TempDir()
+	output, _ := NewFileOutput(FileConfig{Filename: filepath.Join(directory, "app.log"), MaxSize: 4096})
+	rootLogger := NewLogger(&Config{Encoder: TextEncoder{}, Writer: output, DateFormat: TIME_FORMAT})
+
+	waitGroup := new(sync.WaitGroup)
+	for i := 0; i < goroutines; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			logger, _ := rootLogger.ChildLogger("worker", nil)
+			for j := 0; j < records; j++ {
+				logger.Info("message", nil)
+			}
+		}()
+	}
+	waitGroup.Wait()
+	output.Close()
+
+	files, _ := output.backups()
+	files = append(files, filepath.Join(directory, "app.log"))
+	lines := 0
+	for _, name := range files {
+		content, _ := ioutil.ReadFile(name)
+		for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+			if !strings.Contains(line, "\tINFO\tworker\tmessage\t") {
+				t.Fatalf("expected an intact record, actual: %q", line)
+			}
+			lines++
+		}
+	}
+	if lines != goroutines*records {
+		t.Errorf("expected %d records, actual: %d", goroutines*records, lines)
+	}
+}
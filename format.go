@@ -1,45 +1,91 @@
 package log
 
 import (
-	"fmt"
+	"strconv"
 	"time"
-	"strings"
-	"sort"
 )
 
-func JsonFormatter(level string, message string, tags map[string]string, dateFormat string) string {
-	outputMessage := fmt.Sprintf(
-		"{\"time\":\"%s\",\"level\":\"%s\",\"message\":\"%s\"",
-		time.Now().UTC().Format(dateFormat),
-		level,
-		message,
-	)
-	for name, value := range tags {
-		outputMessage += fmt.Sprintf(",\"%s\":\"%s\"", name, value)
-	}
-	return outputMessage + "}"
+// JsonFormatter renders one JSON object per record. Tags follow the time, level and
+// message keys in sorted order; a tag key that consists of underscores followed by
+// one of those keys gets another underscore prepended to stay unique.
+func JsonFormatter(t time.Time, level string, message string, fields Fields, dateFormat string) string {
+	function, _ := fields.Get("function")
+	return formatWithEncoder(JsonEncoder{}, t, level, message, function.String(), fields, dateFormat)
 }
 
-func TextFormatter(level string, message string, tags map[string]string, dateFormat string) string {
-	outputMessage := fmt.Sprintf(
-		"%s\t%s\t%s\t%s",
-		time.Now().UTC().Format(dateFormat),
-		level,
-		tags["function"],
-		message,
-	)
+// TextFormatter renders tab separated time, level, function and message columns
+// followed by the sorted tags as key:value pairs. Object tags are flattened into
+// dotted keys. Separators and control characters are escaped with backslashes,
+// ParseText reads the records back.
+func TextFormatter(t time.Time, level string, message string, fields Fields, dateFormat string) string {
+	function, _ := fields.Get("function")
+	return formatWithEncoder(TextEncoder{}, t, level, message, function.String(), fields, dateFormat)
+}
 
-	outputMessage = outputMessage + "\t"
-	keys := make([]string, len(tags))
-	for key, _ := range tags {
-		keys = append(keys, key)
+func formatWithEncoder(encoder Encoder, t time.Time, level string, message string, function string, fields Fields, dateFormat string) string {
+	record := &Record{Time: t, Level: level, Message: message, Function: function, Fields: fields, DateFormat: dateFormat}
+	return string(appendWithEncoder(make([]byte, 0, 256), encoder, record))
+}
+
+func appendTextField(dst []byte, prefix string, field Field) []byte {
+	if field.Value.Kind() == KindObject && len(field.Value.Object()) > 0 {
+		for _, nested := range field.Value.Object() {
+			dst = appendTextField(dst, prefix+field.Key+".", nested)
+		}
+		return dst
 	}
-	sort.Strings(keys)
+	dst = appendTextEscaped(dst, prefix, textKeySeparators)
+	dst = appendTextEscaped(dst, field.Key, textKeySeparators)
+	dst = append(dst, ':')
+	if field.Value.Kind() == KindString {
+		dst = appendTextEscaped(dst, field.Value.str, textValueSeparators)
+	} else {
+		start := len(dst)
+		dst = appendTextValue(dst, field.Value)
+		dst = escapeTextTail(dst, start, textValueSeparators)
+	}
+	return append(dst, ',')
+}
 
-	for _, key := range keys {
-		if key != "function" && len(key) > 0 {
-			outputMessage += fmt.Sprintf("%s:%s,", key, tags[key])
+func appendTextValue(dst []byte, value Value) []byte {
+	switch value.Kind() {
+	case KindString:
+		return append(dst, value.str...)
+	case KindInt64:
+		return strconv.AppendInt(dst, value.Int64(), 10)
+	case KindFloat64:
+		return strconv.AppendFloat(dst, value.Float64(), 'g', -1, 64)
+	case KindBool:
+		return strconv.AppendBool(dst, value.Bool())
+	case KindTime:
+		return value.Time().AppendFormat(dst, time.RFC3339Nano)
+	case KindDuration:
+		return append(dst, value.Duration().String()...)
+	case KindError:
+		if err := value.Err(); err != nil {
+			return appendTextError(dst, err)
+		}
+		return append(dst, "<nil>"...)
+	case KindObject:
+		dst = append(dst, '{')
+		for i, field := range value.Object() {
+			if i > 0 {
+				dst = append(dst, ' ')
+			}
+			dst = append(dst, field.Key...)
+			dst = append(dst, ':')
+			dst = appendTextValue(dst, field.Value)
+		}
+		return append(dst, '}')
+	case KindArray:
+		dst = append(dst, '[')
+		for i, element := range value.Array() {
+			if i > 0 {
+				dst = append(dst, ' ')
+			}
+			dst = appendTextValue(dst, element)
 		}
+		return append(dst, ']')
 	}
-	return strings.Trim(outputMessage, ",")
+	return dst
 }
@@ -1,22 +1,26 @@
 package log
 
 import (
-	"testing"
 	"encoding/json"
-	"time"
+	"errors"
+	"math"
 	"strings"
+	"testing"
+	"time"
 )
 
+var testTime = time.Date(2017, 1, 2, 3, 4, 5, 678901000, time.UTC)
+
 type JsonLogMessage struct {
-	Time string
-	Level string
-	Message string
-	Tag1 string
+	Time      string
+	Level     string
+	Message   string
+	Tag1      string
 	Something string
 }
 
 func TestJsonFormatterFormatsLevelAndMessageIntoValidJSON(t *testing.T) {
-	resultString := JsonFormatter("LOG_LEVEL", "some message", map[string]string{}, "2006")
+	resultString := JsonFormatter(testTime, "LOG_LEVEL", "some message", Fields{}, "2006")
 	resultJson := new(JsonLogMessage)
 	err := json.Unmarshal([]byte(resultString), resultJson)
 
@@ -33,9 +37,10 @@ func TestJsonFormatterFormatsLevelAndMessageIntoValidJSON(t *testing.T) {
 	}
 }
 
-func TestJsonFormatterFormatsDateInUTC(t *testing.T) {
-	const DATE_FORMAT_STRING = "2006-01-02T15"
-	resultString := JsonFormatter("LOG_LEVEL", "some message", map[string]string{}, DATE_FORMAT_STRING)
+func TestJsonFormatterFormatsTheGivenTimeInItsZone(t *testing.T) {
+	const DATE_FORMAT_STRING = "2006-01-02T15Z07:00"
+	recordTime := time.Date(2017, 1, 2, 23, 4, 5, 0, time.FixedZone("CET", 3600))
+	resultString := JsonFormatter(recordTime, "LOG_LEVEL", "some message", Fields{}, DATE_FORMAT_STRING)
 	resultJson := new(JsonLogMessage)
 	err := json.Unmarshal([]byte(resultString), resultJson)
 
@@ -43,14 +48,13 @@ func TestJsonFormatterFormatsDateInUTC(t *testing.T) {
 		t.Errorf("json.Unmarshal failed with error: %s", err.Error())
 	}
 
-	currentDateUTC := time.Now().UTC()
-	if resultJson.Time != currentDateUTC.Format(DATE_FORMAT_STRING) {
-		t.Errorf("expected time: \"%s\", actual: \"%s\"", currentDateUTC.Format(DATE_FORMAT_STRING), resultJson.Time)
+	if resultJson.Time != "2017-01-02T23+01:00" {
+		t.Errorf("expected time: \"%s\", actual: \"%s\"", "2017-01-02T23+01:00", resultJson.Time)
 	}
 }
 
 func TestJsonFormatterAppendsAllTagsToMessage(t *testing.T) {
-	resultString := JsonFormatter("LOG_LEVEL", "some message", map[string]string{"tag1":"value1", "something":"anything"}, "2006")
+	resultString := JsonFormatter(testTime, "LOG_LEVEL", "some message", Fields{String("tag1", "value1"), String("something", "anything")}, "2006")
 	resultJson := new(JsonLogMessage)
 	err := json.Unmarshal([]byte(resultString), resultJson)
 
@@ -63,16 +67,125 @@ func TestJsonFormatterAppendsAllTagsToMessage(t *testing.T) {
 	}
 }
 
+func TestJsonFormatterEscapesSpecialCharacters(t *testing.T) {
+	message := "quote\" backslash\\ newline\n tab\t control\x01 separator\u2028"
+	resultString := JsonFormatter(testTime, "LOG_LEVEL", message, Fields{String("tag\"1", "line1\nline2")}, "2006")
+	result := map[string]string{}
+	err := json.Unmarshal([]byte(resultString), &result)
+
+	if err != nil {
+		t.Fatalf("json.Unmarshal failed with error: %s, output: %s", err.Error(), resultString)
+	}
+
+	if result["message"] != message {
+		t.Errorf("expected message: %q, actual: %q", message, result["message"])
+	}
+
+	if result["tag\"1"] != "line1\nline2" {
+		t.Errorf("expected tag value: %q, actual: %q", "line1\nline2", result["tag\"1"])
+	}
+
+	if strings.ContainsAny(resultString, "\n\r") {
+		t.Errorf("expected output on a single line, actual: %q", resultString)
+	}
+}
+
+func TestJsonFormatterReplacesInvalidUTF8(t *testing.T) {
+	resultString := JsonFormatter(testTime, "LOG_LEVEL", "invalid\xff", Fields{}, "2006")
+	result := map[string]string{}
+	err := json.Unmarshal([]byte(resultString), &result)
+
+	if err != nil {
+		t.Fatalf("json.Unmarshal failed with error: %s, output: %s", err.Error(), resultString)
+	}
+
+	if result["message"] != "invalid\ufffd" {
+		t.Errorf("expected message: %q, actual: %q", "invalid\ufffd", result["message"])
+	}
+}
+
+func TestJsonFormatterSortsTagKeys(t *testing.T) {
+	resultString := JsonFormatter(testTime, "L", "m", Fields{String("c", "3"), String("a", "1"), String("b", "2")}, "2006")
+	expected := `,"level":"L","message":"m","a":"1","b":"2","c":"3"}`
+
+	if !strings.HasSuffix(resultString, expected) {
+		t.Errorf("expected output to end with: %s, actual: %s", expected, resultString)
+	}
+}
+
+func TestJsonFormatterRenamesTagsCollidingWithReservedKeys(t *testing.T) {
+	resultString := JsonFormatter(testTime, "L", "m", Fields{String("message", "tag"), String("_message", "other")}, "2006")
+	result := map[string]string{}
+	err := json.Unmarshal([]byte(resultString), &result)
+
+	if err != nil {
+		t.Fatalf("json.Unmarshal failed with error: %s, output: %s", err.Error(), resultString)
+	}
+
+	if result["message"] != "m" || result["_message"] != "tag" || result["__message"] != "other" {
+		t.Errorf("expected colliding tag to be renamed, actual: %s", resultString)
+	}
+}
+
+func FuzzJsonFormatter(f *testing.F) {
+	f.Add("INFO", "message", "key", "value")
+	f.Add("DEBUG", "\"quoted\"\n", "\\", "\x00\x1f")
+	f.Add("L", "invalid \xff\xfe utf8", "time", "\u2028\u2029")
+	f.Fuzz(func(t *testing.T, level string, message string, key string, value string) {
+		resultString := JsonFormatter(testTime, level, message, Fields{String(key, value)}, "2006")
+
+		if strings.ContainsAny(resultString, "\n\r") {
+			t.Fatalf("expected output on a single line, actual: %q", resultString)
+		}
+
+		result := map[string]string{}
+		if err := json.Unmarshal([]byte(resultString), &result); err != nil {
+			t.Fatalf("json.Unmarshal failed with error: %s, output: %q", err.Error(), resultString)
+		}
+
+		expectedKey := key
+		if isReservedJsonKey(expectedKey) {
+			expectedKey = "_" + expectedKey
+		}
+		expected := map[string]string{
+			"level":                   roundTrip(t, level),
+			"message":                 roundTrip(t, message),
+			roundTrip(t, expectedKey): roundTrip(t, value),
+		}
+		for name, expectedValue := range expected {
+			if result[name] != expectedValue {
+				t.Errorf("expected %q to be %q, actual: %q", name, expectedValue, result[name])
+			}
+		}
+		if len(result) != 4 {
+			t.Errorf("expected exactly 4 keys, actual: %q", resultString)
+		}
+	})
+}
+
+// roundTrip returns s as encoding/json would decode it after encoding, which
+// is how invalid UTF-8 is expected to come back.
+func roundTrip(t *testing.T, s string) string {
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded string
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
 
 func TestTextFormatterCreatesTheCorrectFormat(t *testing.T) {
-	resultString := TextFormatter("LOG_LEVEL", "some message", map[string]string{"function":"function name", "something":"anything"}, "2006")
+	resultString := TextFormatter(testTime, "LOG_LEVEL", "some message", Fields{String("function", "function name"), String("something", "anything")}, "2006")
 	resultParts := strings.Split(resultString, "\t")
 
 	if len(resultParts) != 5 {
 		t.Errorf("expected parts count: %d, actual: %d", 5, len(resultParts))
 	}
 
-	if resultParts[0] != time.Now().UTC().Format("2006") {
+	if resultParts[0] != testTime.Format("2006") {
 		t.Errorf("expected first message part to be timestamp, actual: \"%s\"", resultParts[0])
 	}
 
@@ -93,21 +206,113 @@ func TestTextFormatterCreatesTheCorrectFormat(t *testing.T) {
 	}
 }
 
-func TestTextFormatterOutputsTimestampInUTC(t *testing.T) {
-	const DATE_FORMAT_STRING = "2006-01-02T15"
-	resultString := TextFormatter("LOG_LEVEL", "some message", map[string]string{"function":"function name", "something":"anything"}, DATE_FORMAT_STRING)
+func TestTextFormatterFormatsTheGivenTimeInItsZone(t *testing.T) {
+	const DATE_FORMAT_STRING = "2006-01-02T15Z07:00"
+	recordTime := time.Date(2017, 1, 2, 23, 4, 5, 0, time.FixedZone("CET", 3600))
+	resultString := TextFormatter(recordTime, "LOG_LEVEL", "some message", Fields{String("function", "function name"), String("something", "anything")}, DATE_FORMAT_STRING)
 	resultParts := strings.Split(resultString, "\t")
 
-	if resultParts[0] != time.Now().UTC().Format(DATE_FORMAT_STRING) {
-		t.Errorf("expected time: \"%s\", actual: \"%s\"", time.Now().UTC().Format(DATE_FORMAT_STRING), resultParts[0])
+	if resultParts[0] != "2017-01-02T23+01:00" {
+		t.Errorf("expected time: \"%s\", actual: \"%s\"", "2017-01-02T23+01:00", resultParts[0])
 	}
 }
 
 func TestTextFormatterSortsTagsAlphabetically(t *testing.T) {
-	resultString := TextFormatter("L", "s", map[string]string{"function":"function name", "something":"anything", "before":"tag1"}, "2006")
+	resultString := TextFormatter(testTime, "L", "s", Fields{String("function", "function name"), String("something", "anything"), String("before", "tag1")}, "2006")
 	resultParts := strings.Split(resultString, "\t")
 
 	if resultParts[4] != "before:tag1,something:anything" {
 		t.Errorf("expected tags to be: \"%s\", actual: \"%s\"", "before:tag1,something:anything", resultParts[5])
 	}
 }
+
+func TestJsonFormatterRendersNativeTypes(t *testing.T) {
+	timestamp := time.Date(2016, 7, 14, 13, 9, 51, 0, time.UTC)
+	resultString := JsonFormatter(testTime, "L", "m", Fields{
+		String("string", "value"),
+		Int64("int", -42),
+		Float64("float", 1.5),
+		Bool("bool", true),
+		Time("time_tag", timestamp),
+		Duration("duration", time.Second),
+		Err("error", errors.New("failed")),
+		Object("object", Int("a", 1), String("b", "2")),
+		Array("array", Int64Value(1), StringValue("two")),
+	}, "2006")
+	expected := `"array":[1,"two"],"bool":true,"duration":1000000000,"error":{"message":"failed","type":"*errors.errorString"},"float":1.5,"int":-42,` +
+		`"object":{"a":1,"b":"2"},"string":"value","time_tag":"2016-07-14T13:09:51Z"}`
+
+	if !strings.HasSuffix(resultString, expected) {
+		t.Errorf("expected output to end with: %s, actual: %s", expected, resultString)
+	}
+}
+
+func TestJsonFormatterRendersNonFiniteFloatsAsStrings(t *testing.T) {
+	resultString := JsonFormatter(testTime, "L", "m", Fields{Float64("nan", math.NaN()), Float64("inf", math.Inf(1))}, "2006")
+
+	if !strings.HasSuffix(resultString, `"inf":"+Inf","nan":"NaN"}`) {
+		t.Errorf("expected non-finite floats as strings, actual: %s", resultString)
+	}
+	if !json.Valid([]byte(resultString)) {
+		t.Errorf("expected valid JSON, actual: %s", resultString)
+	}
+}
+
+func TestTextFormatterRendersTypedValuesReadably(t *testing.T) {
+	resultString := TextFormatter(testTime, "L", "m", Fields{
+		Int64("int", 42),
+		Bool("bool", false),
+		Duration("duration", 1500*time.Millisecond),
+		Err("error", errors.New("failed")),
+		Object("object", Int("a", 1), Object("b", String("c", "d"))),
+		Array("array", Int64Value(1), StringValue("two")),
+	}, "2006")
+	resultParts := strings.Split(resultString, "\t")
+	expected := "array:[1 two],bool:false,duration:1.5s,error:failed,int:42,object.a:1,object.b.c:d"
+
+	if resultParts[4] != expected {
+		t.Errorf("expected tags: %s, actual: %s", expected, resultParts[4])
+	}
+}
+
+func TestFormattersRenderGoldenOutput(t *testing.T) {
+	fields := Fields{
+		String("program", "shop"),
+		String("function", "checkout"),
+		String("order", "A-17"),
+		Int("items", 3),
+		Duration("elapsed", 1250*time.Millisecond),
+		Object("user", Int("id", 42), Bool("admin", false)),
+	}
+	goldens := map[string]string{
+		"json": `{"time":"2017-01-02T03:04:05.678901","level":"INFO","message":"order placed","elapsed":1250000000,` +
+			`"function":"checkout","items":3,"order":"A-17","program":"shop","user":{"id":42,"admin":false}}`,
+		"text": "2017-01-02T03:04:05.678901\tINFO\tcheckout\torder placed\t" +
+			"elapsed:1.25s,items:3,order:A-17,program:shop,user.id:42,user.admin:false",
+		"logfmt": `time=2017-01-02T03:04:05.678901 level=INFO msg="order placed" function=checkout program=shop ` +
+			`elapsed=1.25s items=3 order=A-17 user.id=42 user.admin=false`,
+		"console": "2017-01-02T03:04:05.678901 INFO  checkout: order placed" + strings.Repeat(" ", 28) +
+			" elapsed=1.25s items=3 order=A-17 program=shop user.id=42 user.admin=false",
+	}
+	formatters := map[string]Formatter{
+		"json":    FormatterFunc(JsonFormatter),
+		"text":    FormatterFunc(TextFormatter),
+		"logfmt":  FormatterFunc(LogfmtFormatter),
+		"console": ConsoleFormatter{},
+	}
+	record := &Record{
+		Time:       testTime,
+		Level:      "INFO",
+		Message:    "order placed",
+		Program:    "shop",
+		Function:   "checkout",
+		Fields:     fields,
+		DateFormat: TIME_FORMAT,
+	}
+
+	for name, formatter := range formatters {
+		if result := string(formatter.Format(nil, record)); result != goldens[name] {
+			t.Errorf("%s: expected %q, actual: %q", name, goldens[name], result)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LevelHandler serves the state of an AtomicLevel as JSON on GET and changes
+// it on PUT. A PUT request may set revert_after to a positive duration, e.g.
+// "15m", after which the levels it changed are restored to their previous
+// values.
+// A function level set to the empty string is cleared.
+//
+//	PUT {"level":"INFO","functions":{"handlePayment":"DEBUG"},"revert_after":"15m"}
+type LevelHandler struct {
+	level   *AtomicLevel
+	mutex   sync.Mutex
+	reverts map[revertKey]*levelRevert
+}
+
+// InvalidRevertAfter is returned for a revert_after that is not a positive
+// duration.
+type InvalidRevertAfter string
+
+func (err InvalidRevertAfter) Error() string {
+	return "invalid revert_after: \"" + string(err) + "\". Must be a positive duration like \"15m\""
+}
+
+// revertKey identifies the global level or the level of a function.
+type revertKey struct {
+	function bool
+	name     string
+}
+
+// levelRevert restores a level that was changed temporarily.
+type levelRevert struct {
+	previous string
+	set      bool
+	at       time.Time
+	timer    *time.Timer
+}
+
+type levelState struct {
+	Level             string               `json:"level"`
+	Functions         map[string]string    `json:"functions"`
+	KnownFunctions    []string             `json:"known_functions"`
+	RevertLevelAt     *time.Time           `json:"revert_level_at,omitempty"`
+	RevertFunctionsAt map[string]time.Time `json:"revert_functions_at,omitempty"`
+}
+
+type levelChange struct {
+	Level       string            `json:"level"`
+	Functions   map[string]string `json:"functions"`
+	RevertAfter string            `json:"revert_after"`
+}
+
+func NewLevelHandler(level *AtomicLevel) *LevelHandler {
+	return &LevelHandler{level: level, reverts: map[revertKey]*levelRevert{}}
+}
+
+func (lh *LevelHandler) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if err := lh.change(request); err != nil {
+			writeJSON(response, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	default:
+		response.Header().Set("Allow", "GET, PUT")
+		writeJSON(response, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(response, http.StatusOK, lh.state())
+}
+
+func (lh *LevelHandler) change(request *http.Request) error {
+	change := new(levelChange)
+	if err := json.NewDecoder(request.Body).Decode(change); err != nil {
+		return err
+	}
+
+	var revertAfter time.Duration
+	if change.RevertAfter != "" {
+		var err error
+		if revertAfter, err = time.ParseDuration(change.RevertAfter); err != nil {
+			return err
+		}
+		if revertAfter <= 0 {
+			return InvalidRevertAfter(change.RevertAfter)
+		}
+	}
+	if change.Level != "" && !lh.level.isValid(change.Level) {
+		return InvalidLevel(change.Level)
+	}
+	for _, level := range change.Functions {
+		if level != "" && !lh.level.isValid(level) {
+			return InvalidLevel(level)
+		}
+	}
+
+	lh.mutex.Lock()
+	defer lh.mutex.Unlock()
+	if change.Level != "" {
+		lh.scheduleRevert(revertKey{}, lh.level.Level(), true, revertAfter)
+		lh.level.SetLevel(change.Level)
+	}
+	for function, level := range change.Functions {
+		previous, set := lh.level.FunctionLevel(function)
+		lh.scheduleRevert(revertKey{true, function}, previous, set, revertAfter)
+		if level == "" {
+			lh.level.ClearFunctionLevel(function)
+		} else {
+			lh.level.SetFunctionLevel(function, level)
+		}
+	}
+	return nil
+}
+
+// scheduleRevert must be called with the mutex held, before the level is
+// changed. A pending revert keeps the value it restores, so repeated
+// temporary changes still end with the level from before the first one.
+// A change without revertAfter cancels the pending revert.
+func (lh *LevelHandler) scheduleRevert(key revertKey, previous string, set bool, revertAfter time.Duration) {
+	pending, ok := lh.reverts[key]
+	if ok {
+		pending.timer.Stop()
+		delete(lh.reverts, key)
+		previous, set = pending.previous, pending.set
+	}
+	if revertAfter <= 0 {
+		return
+	}
+
+	revert := &levelRevert{previous: previous, set: set, at: time.Now().Add(revertAfter)}
+	revert.timer = time.AfterFunc(revertAfter, func() {
+		lh.mutex.Lock()
+		defer lh.mutex.Unlock()
+		if lh.reverts[key] != revert {
+			return
+		}
+		delete(lh.reverts, key)
+		switch {
+		case !key.function:
+			// the previous level may be the unvalidated Config.Level
+			lh.level.level.Store(previous)
+		case set:
+			lh.level.SetFunctionLevel(key.name, previous)
+		default:
+			lh.level.ClearFunctionLevel(key.name)
+		}
+	})
+	lh.reverts[key] = revert
+}
+
+func (lh *LevelHandler) state() levelState {
+	lh.mutex.Lock()
+	defer lh.mutex.Unlock()
+	state := levelState{
+		Level:          lh.level.Level(),
+		Functions:      lh.level.FunctionLevels(),
+		KnownFunctions: lh.level.KnownFunctions(),
+	}
+	for key, revert := range lh.reverts {
+		at := revert.at.UTC()
+		if !key.function {
+			state.RevertLevelAt = &at
+			continue
+		}
+		if state.RevertFunctionsAt == nil {
+			state.RevertFunctionsAt = map[string]time.Time{}
+		}
+		state.RevertFunctionsAt[key.name] = at
+	}
+	return state
+}
+
+func writeJSON(response http.ResponseWriter, status int, body interface{}) {
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(status)
+	json.NewEncoder(response).Encode(body)
+}
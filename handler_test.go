@@ -0,0 +1,141 @@
+package log_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flowpl/log"
+)
+
+type levelResponse struct {
+	Level             string               `json:"level"`
+	Functions         map[string]string    `json:"functions"`
+	KnownFunctions    []string             `json:"known_functions"`
+	RevertLevelAt     *time.Time           `json:"revert_level_at"`
+	RevertFunctionsAt map[string]time.Time `json:"revert_functions_at"`
+	Error             string               `json:"error"`
+}
+
+func serveLevelRequest(t *testing.T, handler http.Handler, method string, body string) (int, *levelResponse) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, "/log/level", strings.NewReader(body)))
+	response := new(levelResponse)
+	if err := json.Unmarshal(recorder.Body.Bytes(), response); err != nil {
+		t.Fatalf("json.Unmarshal failed with error: %s, body: %s", err.Error(), recorder.Body.String())
+	}
+	return recorder.Code, response
+}
+
+func TestLevelHandler_GetShouldListLevelsAndKnownFunctions(t *testing.T) {
+	config := &log.Config{Level: log.LEVEL_INFO, FunctionName: "main"}
+	rootLogger := log.NewLogger(config)
+	rootLogger.ChildLogger("handlePayment", nil)
+	config.AtomicLevel.SetFunctionLevel("handlePayment", log.LEVEL_DEBUG)
+
+	status, response := serveLevelRequest(t, log.NewLevelHandler(config.AtomicLevel), http.MethodGet, "")
+
+	if status != http.StatusOK {
+		t.Errorf("expected status %d, actual: %d", http.StatusOK, status)
+	}
+	if response.Level != log.LEVEL_INFO {
+		t.Errorf("expected level INFO, actual: %s", response.Level)
+	}
+	if response.Functions["handlePayment"] != log.LEVEL_DEBUG {
+		t.Errorf("expected handlePayment to be DEBUG, actual: %v", response.Functions)
+	}
+	if strings.Join(response.KnownFunctions, ",") != "handlePayment,main" {
+		t.Errorf("expected known functions handlePayment,main, actual: %v", response.KnownFunctions)
+	}
+}
+
+func TestLevelHandler_PutShouldChangeLevels(t *testing.T) {
+	level := log.NewAtomicLevel(log.LEVEL_INFO)
+	level.SetFunctionLevel("handleOrder", log.LEVEL_DEBUG)
+	handler := log.NewLevelHandler(level)
+
+	status, response := serveLevelRequest(t, handler, http.MethodPut, `{"level":"DEBUG","functions":{"handlePayment":"DEBUG","handleOrder":""}}`)
+
+	if status != http.StatusOK {
+		t.Errorf("expected status %d, actual: %d, error: %s", http.StatusOK, status, response.Error)
+	}
+	if level.Level() != log.LEVEL_DEBUG || response.Level != log.LEVEL_DEBUG {
+		t.Errorf("expected level DEBUG, actual: %s", level.Level())
+	}
+	if functionLevel, _ := level.FunctionLevel("handlePayment"); functionLevel != log.LEVEL_DEBUG {
+		t.Errorf("expected handlePayment to be DEBUG, actual: %s", functionLevel)
+	}
+	if _, ok := level.FunctionLevel("handleOrder"); ok {
+		t.Error("expected the level of handleOrder to be cleared")
+	}
+}
+
+func TestLevelHandler_PutShouldRejectInvalidRequests(t *testing.T) {
+	level := log.NewAtomicLevel(log.LEVEL_INFO)
+	handler := log.NewLevelHandler(level)
+
+	for _, body := range []string{`{"level":"VERBOSE"}`, `{"functions":{"main":"VERBOSE"}}`, `{"revert_after":"soon"}`, `{"level":"DEBUG","revert_after":"-5m"}`, `{"level":"DEBUG","revert_after":"0s"}`, `not json`} {
+		status, response := serveLevelRequest(t, handler, http.MethodPut, body)
+		if status != http.StatusBadRequest || response.Error == "" {
+			t.Errorf("expected status %d with error for %s, actual: %d", http.StatusBadRequest, body, status)
+		}
+	}
+	if level.Level() != log.LEVEL_INFO || len(level.FunctionLevels()) != 0 {
+		t.Error("expected invalid requests not to change the level")
+	}
+}
+
+func TestLevelHandler_ShouldRejectOtherMethods(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	log.NewLevelHandler(log.NewAtomicLevel(log.LEVEL_INFO)).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if recorder.Code != http.StatusMethodNotAllowed || recorder.Header().Get("Allow") != "GET, PUT" {
+		t.Errorf("expected status %d with Allow header, actual: %d", http.StatusMethodNotAllowed, recorder.Code)
+	}
+}
+
+func TestLevelHandler_PutShouldRevertTemporaryChanges(t *testing.T) {
+	level := log.NewAtomicLevel(log.LEVEL_INFO)
+	handler := log.NewLevelHandler(level)
+
+	_, response := serveLevelRequest(t, handler, http.MethodPut, `{"level":"DEBUG","functions":{"handlePayment":"DEBUG"},"revert_after":"50ms"}`)
+	if response.RevertLevelAt == nil || response.RevertFunctionsAt["handlePayment"].IsZero() {
+		t.Errorf("expected pending reverts in response, actual: %+v", response)
+	}
+	// a second temporary change must not make the first one permanent
+	serveLevelRequest(t, handler, http.MethodPut, `{"level":"DEBUG","revert_after":"50ms"}`)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for level.Level() != log.LEVEL_INFO && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	for len(level.FunctionLevels()) != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if level.Level() != log.LEVEL_INFO {
+		t.Errorf("expected level to be reverted to INFO, actual: %s", level.Level())
+	}
+	if len(level.FunctionLevels()) != 0 {
+		t.Errorf("expected function levels to be reverted, actual: %v", level.FunctionLevels())
+	}
+	if _, response := serveLevelRequest(t, handler, http.MethodGet, ""); response.RevertLevelAt != nil {
+		t.Error("expected no pending revert after it ran")
+	}
+}
+
+func TestLevelHandler_PutWithoutRevertShouldCancelPendingRevert(t *testing.T) {
+	level := log.NewAtomicLevel(log.LEVEL_INFO)
+	handler := log.NewLevelHandler(level)
+
+	serveLevelRequest(t, handler, http.MethodPut, `{"level":"DEBUG","revert_after":"20ms"}`)
+	serveLevelRequest(t, handler, http.MethodPut, `{"level":"DEBUG"}`)
+	time.Sleep(60 * time.Millisecond)
+
+	if level.Level() != log.LEVEL_DEBUG {
+		t.Errorf("expected level to stay DEBUG, actual: %s", level.Level())
+	}
+}
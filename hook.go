@@ -0,0 +1,94 @@
+package log
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+)
+
+// Hook is run on a record before it is formatted. It may inspect the record,
+// add, change or remove fields, change the message or level, or return false
+// to drop the record.
+type Hook interface {
+	Run(record *Record) bool
+}
+
+// HookFunc adapts a function to the Hook interface.
+type HookFunc func(record *Record) bool
+
+func (hf HookFunc) Run(record *Record) bool {
+	return hf(record)
+}
+
+// runHooks runs the hooks of the logger in order until one of them drops the
+// record. Fields added by hooks are redacted like the fields of the call.
+func (log Log) runHooks(record *Record) bool {
+	for _, hook := range log.config.Hooks {
+		if !hook.Run(record) {
+			return false
+		}
+	}
+	if log.config.Redactor != nil {
+		log.config.Redactor.redact(record.Fields)
+	}
+	return true
+}
+
+// Enrich returns a hook that adds fields to every record. Fields of the
+// record with the same key are kept.
+func Enrich(fields ...Field) Hook {
+	return HookFunc(func(record *Record) bool {
+		record.enrich(fields...)
+		return true
+	})
+}
+
+// Hostname adds the host name of the machine as hostname field.
+func Hostname() Hook {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return Enrich(String("hostname", hostname))
+}
+
+// ProcessID adds the id of the process as pid field.
+func ProcessID() Hook {
+	return Enrich(Int("pid", os.Getpid()))
+}
+
+// Version adds the build version of the program as version field.
+func Version(version string) Hook {
+	return Enrich(String("version", version))
+}
+
+// GoroutineID adds the id of the goroutine that writes the record as
+// goroutine field. It is meant for debugging, reading the id is slow.
+func GoroutineID() Hook {
+	return HookFunc(func(record *Record) bool {
+		record.enrich(Int64("goroutine", goroutineID()))
+		return true
+	})
+}
+
+// goroutineID parses the id from the first line of the goroutine stack,
+// "goroutine 18 [running]:".
+func goroutineID() int64 {
+	var buffer [64]byte
+	stack := buffer[:runtime.Stack(buffer[:], false)]
+	const prefix = len("goroutine ")
+	end := prefix
+	for end < len(stack) && stack[end] >= '0' && stack[end] <= '9' {
+		end++
+	}
+	id, _ := strconv.ParseInt(string(stack[prefix:end]), 10, 64)
+	return id
+}
+
+func (record *Record) enrich(fields ...Field) {
+	for _, field := range fields {
+		if _, found := record.Fields.Get(field.Key); !found {
+			record.Fields = append(record.Fields, field)
+		}
+	}
+}
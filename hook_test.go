@@ -0,0 +1,103 @@
+package log_test
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/flowpl/log"
+)
+
+func TestLog_InfoShouldRunHooksInOrderBeforeFormatting(t *testing.T) {
+	output := new(bytes.Buffer)
+	calls := []string{}
+	hook := func(name string) log.Hook {
+		return log.HookFunc(func(record *log.Record) bool {
+			calls = append(calls, name)
+			record.Fields = append(record.Fields, log.String("hooks", strings.Join(calls, ",")))
+			return true
+		})
+	}
+	logger := log.NewLogger(&log.Config{Encoder: log.JsonEncoder{}, Writer: output, Hooks: []log.Hook{hook("first"), hook("second")}})
+
+	logger.Info("message", nil)
+
+	if !strings.Contains(output.String(), `"hooks":"first,second"`) {
+		t.Errorf("expected the hooks to run in order, actual: %q", output.String())
+	}
+}
+
+func TestLog_InfoShouldWriteTheRecordAsChangedByHooks(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	output := new(bytes.Buffer)
+	transform := log.HookFunc(func(record *log.Record) bool {
+		record.Message = strings.ToUpper(record.Message)
+		record.Fields = append(record.Fields, log.String("user", "replaced"))
+		return true
+	})
+	logger := log.NewLogger(&log.Config{
+		Sinks: []log.Sink{
+			{Encoder: log.TextEncoder{}, Writer: output},
+			{Formatter: dfo.createDummyFormatter(), Writer: new(bytes.Buffer)},
+		},
+		Tags:  log.Fields{log.String("user", "tag")},
+		Hooks: []log.Hook{transform},
+	})
+
+	logger.Info("message", nil)
+
+	if !strings.Contains(output.String(), "\tMESSAGE\t") || !strings.Contains(output.String(), "user:replaced") {
+		t.Errorf("expected the changed record from the encoder, actual: %q", output.String())
+	}
+	if dfo.message != "MESSAGE" || dfo.tag("user") != "replaced" {
+		t.Errorf("expected the changed record from the formatter, actual: %q %v", dfo.message, dfo.tags)
+	}
+}
+
+func TestLog_InfoShouldDropRecordsVetoedByHooks(t *testing.T) {
+	output := new(bytes.Buffer)
+	ran := false
+	veto := log.HookFunc(func(record *log.Record) bool {
+		_, health := record.Fields.Get("health_check")
+		return !health
+	})
+	after := log.HookFunc(func(record *log.Record) bool {
+		ran = true
+		return true
+	})
+	logger := log.NewLogger(&log.Config{Encoder: log.JsonEncoder{}, Writer: output, Hooks: []log.Hook{veto, after}})
+
+	err := logger.Info("ping", log.Bool("health_check", true))
+
+	if err != nil || output.Len() != 0 || ran {
+		t.Errorf("expected the record to be dropped, actual: %v %q %v", err, output.String(), ran)
+	}
+}
+
+func TestLog_ChildLoggerShouldRunTheHooksOfItsParent(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	logger := log.NewLogger(&log.Config{
+		Formatter: dfo.createDummyFormatter(),
+		Output:    dfo.createDummyOutput(),
+		Hooks:     []log.Hook{log.Hostname(), log.ProcessID(), log.Version("1.2.3"), log.GoroutineID()},
+		Redactor:  &log.Redactor{Keys: []string{"hostname"}},
+	})
+	child, _ := logger.ChildLogger("child", nil)
+
+	child.Info("message", log.With("version", "overridden"))
+
+	if dfo.tag("pid") != strconv.Itoa(os.Getpid()) {
+		t.Errorf("expected the pid, actual: %q", dfo.tag("pid"))
+	}
+	if dfo.tag("version") != "overridden" {
+		t.Errorf("expected enrichers to keep the fields of the call, actual: %q", dfo.tag("version"))
+	}
+	if dfo.tag("hostname") != log.RedactedMask {
+		t.Errorf("expected fields of hooks to be redacted, actual: %q", dfo.tag("hostname"))
+	}
+	if id, err := strconv.Atoi(dfo.tag("goroutine")); err != nil || id <= 0 {
+		t.Errorf("expected the goroutine id, actual: %q", dfo.tag("goroutine"))
+	}
+}
@@ -0,0 +1,124 @@
+package log
+
+import (
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s to dst as a quoted JSON string. The escaping
+// follows encoding/json: control characters, quotes and backslashes are
+// escaped, U+2028 and U+2029 are escaped for the benefit of javascript
+// consumers and every byte of invalid UTF-8 is replaced with U+FFFD.
+// The result never contains a raw line break.
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	dst = appendJSONStringContent(dst, s)
+	return append(dst, '"')
+}
+
+func appendJSONStringContent(dst []byte, s string) []byte {
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				dst = append(dst, '\\', b)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	return append(dst, s[start:]...)
+}
+
+func needsJSONEscape(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 || c == '"' || c == '\\' || c >= utf8.RuneSelf {
+			return true
+		}
+	}
+	return false
+}
+
+func appendJSONValue(dst []byte, value Value) []byte {
+	switch value.Kind() {
+	case KindString:
+		return appendJSONString(dst, value.str)
+	case KindInt64:
+		return strconv.AppendInt(dst, value.Int64(), 10)
+	case KindFloat64:
+		f := value.Float64()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			// JSON has no representation for these, keep them readable
+			return appendJSONString(dst, strconv.FormatFloat(f, 'g', -1, 64))
+		}
+		return strconv.AppendFloat(dst, f, 'g', -1, 64)
+	case KindBool:
+		return strconv.AppendBool(dst, value.Bool())
+	case KindTime:
+		dst = append(dst, '"')
+		dst = value.Time().AppendFormat(dst, time.RFC3339Nano)
+		return append(dst, '"')
+	case KindDuration:
+		return strconv.AppendInt(dst, int64(value.Duration()), 10)
+	case KindError:
+		if err := value.Err(); err != nil {
+			return appendJSONError(dst, err)
+		}
+	case KindObject:
+		dst = append(dst, '{')
+		for i, field := range value.Object() {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendJSONString(dst, field.Key)
+			dst = append(dst, ':')
+			dst = appendJSONValue(dst, field.Value)
+		}
+		return append(dst, '}')
+	case KindArray:
+		dst = append(dst, '[')
+		for i, element := range value.Array() {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendJSONValue(dst, element)
+		}
+		return append(dst, ']')
+	}
+	return appendJSONString(dst, value.String())
+}
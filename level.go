@@ -0,0 +1,182 @@
+package log
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+type InvalidLevel string
+
+func (err InvalidLevel) Error() string {
+	return "invalid log level: \"" + string(err) + "\". Must be INFO or DEBUG, or TRACE, WARN, ERROR or FATAL with extended levels"
+}
+
+// AtomicLevel is a log level that can be changed while the program is running.
+// All loggers created from one root logger share its AtomicLevel. Levels set
+// for a function name apply to the loggers of that function and their child
+// loggers, the override of the innermost function wins.
+type AtomicLevel struct {
+	level     atomic.Value // string
+	overrides atomic.Value // map[string]string, replaced on every change
+	mutex     sync.Mutex
+	functions sync.Map // function names of all loggers using the level
+	extended  uint32   // 1 if TRACE, WARN, ERROR and FATAL are valid
+}
+
+func NewAtomicLevel(level string) *AtomicLevel {
+	atomicLevel := new(AtomicLevel)
+	atomicLevel.level.Store(level)
+	atomicLevel.overrides.Store(map[string]string{})
+	return atomicLevel
+}
+
+func (al *AtomicLevel) Level() string {
+	return al.level.Load().(string)
+}
+
+func (al *AtomicLevel) SetLevel(level string) error {
+	if !al.isValid(level) {
+		return InvalidLevel(level)
+	}
+	al.level.Store(level)
+	return nil
+}
+
+func (al *AtomicLevel) FunctionLevel(function string) (string, bool) {
+	level, ok := al.loadOverrides()[function]
+	return level, ok
+}
+
+func (al *AtomicLevel) SetFunctionLevel(function string, level string) error {
+	if !al.isValid(level) {
+		return InvalidLevel(level)
+	}
+	al.updateOverrides(func(overrides map[string]string) {
+		overrides[function] = level
+	})
+	return nil
+}
+
+func (al *AtomicLevel) ClearFunctionLevel(function string) {
+	al.updateOverrides(func(overrides map[string]string) {
+		delete(overrides, function)
+	})
+}
+
+// FunctionLevels returns a copy of all per function levels.
+func (al *AtomicLevel) FunctionLevels() map[string]string {
+	overrides := al.loadOverrides()
+	levels := make(map[string]string, len(overrides))
+	for function, level := range overrides {
+		levels[function] = level
+	}
+	return levels
+}
+
+// KnownFunctions returns the sorted function names of all loggers that use
+// the level.
+func (al *AtomicLevel) KnownFunctions() []string {
+	functions := []string{}
+	al.functions.Range(func(function, _ interface{}) bool {
+		functions = append(functions, function.(string))
+		return true
+	})
+	sort.Strings(functions)
+	return functions
+}
+
+func (al *AtomicLevel) registerFunction(function string) {
+	if _, ok := al.functions.Load(function); !ok {
+		al.functions.Store(function, struct{}{})
+	}
+}
+
+// levelFor returns the level of a logger given the function names from the
+// root logger down to the logger itself.
+func (al *AtomicLevel) levelFor(functions []string) string {
+	overrides := al.loadOverrides()
+	if len(overrides) > 0 {
+		for i := len(functions) - 1; i >= 0; i-- {
+			if level, ok := overrides[functions[i]]; ok {
+				return level
+			}
+		}
+	}
+	return al.Level()
+}
+
+func (al *AtomicLevel) loadOverrides() map[string]string {
+	return al.overrides.Load().(map[string]string)
+}
+
+func (al *AtomicLevel) updateOverrides(update func(map[string]string)) {
+	al.mutex.Lock()
+	defer al.mutex.Unlock()
+	overrides := al.FunctionLevels()
+	update(overrides)
+	al.overrides.Store(overrides)
+}
+
+// Extended reports whether the extended levels are valid.
+func (al *AtomicLevel) Extended() bool {
+	return atomic.LoadUint32(&al.extended) == 1
+}
+
+// EnableExtendedLevels makes TRACE, WARN, ERROR and FATAL valid levels.
+func (al *AtomicLevel) EnableExtendedLevels() {
+	atomic.StoreUint32(&al.extended, 1)
+}
+
+func (al *AtomicLevel) isValid(level string) bool {
+	if al.Extended() {
+		return levelOrder(level) >= 0
+	}
+	return isValidLevel(level)
+}
+
+func isValidLevel(level string) bool {
+	return level == LEVEL_INFO || level == LEVEL_DEBUG
+}
+
+// levelOrder returns the severity of a level, from 0 for TRACE to 5 for
+// FATAL, or -1 for unknown levels.
+func levelOrder(level string) int {
+	switch level {
+	case LEVEL_TRACE:
+		return 0
+	case LEVEL_DEBUG:
+		return 1
+	case LEVEL_INFO:
+		return 2
+	case LEVEL_WARN:
+		return 3
+	case LEVEL_ERROR:
+		return 4
+	case LEVEL_FATAL:
+		return 5
+	}
+	return -1
+}
+
+// levelEnabled reports whether messages of level are written at the minimum
+// level. With extended levels, levels are ordered by severity and unknown
+// minimum levels are treated as INFO. Otherwise INFO is always written and
+// DEBUG only at DEBUG.
+func levelEnabled(minimum string, level string, extended bool) bool {
+	if !extended {
+		switch level {
+		case LEVEL_INFO:
+			return true
+		case LEVEL_DEBUG:
+			return minimum == LEVEL_DEBUG
+		}
+		return false
+	}
+	order := levelOrder(level)
+	minimumOrder := levelOrder(minimum)
+	if minimumOrder < 0 {
+		minimumOrder = levelOrder(LEVEL_INFO)
+	}
+	return order >= 0 && order >= minimumOrder
+}
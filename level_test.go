@@ -0,0 +1,236 @@
+package log_test
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/flowpl/log"
+)
+
+func TestAtomicLevel_SetLevelShouldApplyToAllChildLoggers(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	config := &log.Config{
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		FunctionName: "main",
+	}
+	rootLogger := log.NewLogger(config)
+	childLogger, _ := rootLogger.ChildLogger("child", nil)
+	grandChildLogger, _ := childLogger.ChildLogger("grandchild", nil)
+
+	grandChildLogger.Debug("before", nil)
+	if dfo.message != "" {
+		t.Errorf("expected no debug message before the level changed, actual: \"%s\"", dfo.message)
+	}
+
+	config.AtomicLevel.SetLevel(log.LEVEL_DEBUG)
+	grandChildLogger.Debug("after", nil)
+	if dfo.message != "after" {
+		t.Errorf("expected debug message after the level changed, actual: \"%s\"", dfo.message)
+	}
+
+	if config.AtomicLevel.Level() != log.LEVEL_DEBUG {
+		t.Errorf("expected level to be DEBUG, actual: %s", config.AtomicLevel.Level())
+	}
+}
+
+func TestAtomicLevel_ShouldBeSharedIfPassedInConfig(t *testing.T) {
+	level := log.NewAtomicLevel(log.LEVEL_INFO)
+	first := log.NewLogger(&log.Config{AtomicLevel: level})
+	second := log.NewLogger(&log.Config{AtomicLevel: level})
+
+	level.SetLevel(log.LEVEL_DEBUG)
+	if !first.Enabled(log.LEVEL_DEBUG) || !second.Enabled(log.LEVEL_DEBUG) {
+		t.Error("expected both loggers to observe the shared level")
+	}
+}
+
+func TestAtomicLevel_SetFunctionLevelShouldApplyToTheFunctionAndItsChildren(t *testing.T) {
+	config := &log.Config{Level: log.LEVEL_INFO, FunctionName: "main"}
+	rootLogger := log.NewLogger(config)
+	paymentLogger, _ := rootLogger.ChildLogger("handlePayment", nil)
+	chargeLogger, _ := paymentLogger.ChildLogger("chargeCard", nil)
+	orderLogger, _ := rootLogger.ChildLogger("handleOrder", nil)
+
+	config.AtomicLevel.SetFunctionLevel("handlePayment", log.LEVEL_DEBUG)
+
+	if !paymentLogger.Enabled(log.LEVEL_DEBUG) || !chargeLogger.Enabled(log.LEVEL_DEBUG) {
+		t.Error("expected debug to be enabled for handlePayment and its children")
+	}
+
+	if rootLogger.Enabled(log.LEVEL_DEBUG) || orderLogger.Enabled(log.LEVEL_DEBUG) {
+		t.Error("expected debug to stay disabled outside of handlePayment")
+	}
+
+	config.AtomicLevel.SetFunctionLevel("chargeCard", log.LEVEL_INFO)
+	if chargeLogger.Enabled(log.LEVEL_DEBUG) {
+		t.Error("expected the innermost function level to win")
+	}
+
+	config.AtomicLevel.ClearFunctionLevel("handlePayment")
+	if paymentLogger.Enabled(log.LEVEL_DEBUG) {
+		t.Error("expected debug to be disabled after clearing the function level")
+	}
+}
+
+func TestAtomicLevel_ShouldRejectInvalidLevels(t *testing.T) {
+	level := log.NewAtomicLevel(log.LEVEL_INFO)
+
+	if _, ok := level.SetLevel("VERBOSE").(log.InvalidLevel); !ok {
+		t.Error("expected InvalidLevel error from SetLevel")
+	}
+
+	if _, ok := level.SetFunctionLevel("main", "VERBOSE").(log.InvalidLevel); !ok {
+		t.Error("expected InvalidLevel error from SetFunctionLevel")
+	}
+
+	if level.Level() != log.LEVEL_INFO || len(level.FunctionLevels()) != 0 {
+		t.Error("expected invalid levels not to be applied")
+	}
+}
+
+func TestAtomicLevel_ShouldAllowChangesWhileLogging(t *testing.T) {
+	config := &log.Config{
+		Level:        log.LEVEL_INFO,
+		Formatter:    log.FormatterFunc(log.TextFormatter),
+		Output:       func(string) {},
+		FunctionName: "main",
+	}
+	rootLogger := log.NewLogger(config)
+	waitGroup := new(sync.WaitGroup)
+	for i := 0; i < 8; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			logger, _ := rootLogger.ChildLogger("worker", nil)
+			for j := 0; j < 200; j++ {
+				logger.Debug("message", nil)
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		config.AtomicLevel.SetLevel(log.LEVEL_DEBUG)
+		config.AtomicLevel.SetFunctionLevel("worker", log.LEVEL_INFO)
+		config.AtomicLevel.ClearFunctionLevel("worker")
+		config.AtomicLevel.SetLevel(log.LEVEL_INFO)
+	}
+	waitGroup.Wait()
+}
+
+func TestLog_ExtendedLevelsShouldBeOrderedBySeverity(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger := log.NewExtendedLogger(&log.Config{Level: log.LEVEL_WARN, Encoder: log.TextEncoder{}, Writer: output, FunctionName: "main"})
+	child, _ := logger.ChildLogger("child", nil)
+	extendedChild := child.(log.ExtendedLogger)
+
+	for _, l := range []log.ExtendedLogger{logger, extendedChild} {
+		l.Trace("trace", nil)
+		l.Debug("debug", nil)
+		l.Info("info", nil)
+		l.Warn("warn", nil)
+		l.Error("error", nil)
+		l.Fatal("fatal", nil)
+	}
+
+	lines := strings.Split(strings.TrimRight(output.String(), "\n"), "\n")
+	levels := []string{}
+	for _, line := range lines {
+		levels = append(levels, strings.Split(line, "\t")[1])
+	}
+	if strings.Join(levels, ",") != "WARN,ERROR,FATAL,WARN,ERROR,FATAL" {
+		t.Errorf("expected WARN and above, actual: %v", levels)
+	}
+	if logger.Enabled(log.LEVEL_INFO) || !logger.Enabled(log.LEVEL_ERROR) {
+		t.Error("expected Enabled to compare the levels")
+	}
+}
+
+func TestLog_ExtendedLevelsShouldBeChangeableAtRuntime(t *testing.T) {
+	output := new(bytes.Buffer)
+	level := log.NewAtomicLevel(log.LEVEL_INFO)
+	logger := log.NewExtendedLogger(&log.Config{AtomicLevel: level, Encoder: log.TextEncoder{}, Writer: output, FunctionName: "main"})
+
+	if err := level.SetFunctionLevel("main", log.LEVEL_TRACE); err != nil {
+		t.Fatal(err)
+	}
+	logger.Trace("trace", nil)
+	if err := level.SetLevel(log.LEVEL_ERROR); err != nil {
+		t.Fatal(err)
+	}
+	level.ClearFunctionLevel("main")
+	logger.Warn("warn", nil)
+
+	if !strings.Contains(output.String(), "\tTRACE\t") || strings.Contains(output.String(), "\tWARN\t") {
+		t.Errorf("unexpected output %q", output.String())
+	}
+}
+
+func TestLog_ExtendedLevelsShouldRequireOptIn(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	logger := log.NewLogger(&log.Config{Level: log.LEVEL_DEBUG, Formatter: dfo.createDummyFormatter(), Output: dfo.createDummyOutput()})
+	level := log.NewAtomicLevel(log.LEVEL_INFO)
+
+	err := logger.(log.ExtendedLogger).Warn("warn", nil)
+
+	if err != log.InvalidLevel(log.LEVEL_WARN) || dfo.outputMessage != "" {
+		t.Errorf("expected InvalidLevel without output, actual: %v %q", err, dfo.outputMessage)
+	}
+	if logger.Enabled(log.LEVEL_WARN) || logger.Enabled(log.LEVEL_TRACE) {
+		t.Error("expected extended levels to be disabled")
+	}
+	if level.SetLevel(log.LEVEL_WARN) == nil {
+		t.Error("expected InvalidLevel from SetLevel")
+	}
+}
+
+func TestLog_SinksShouldCompareExtendedLevels(t *testing.T) {
+	alerts := new(bytes.Buffer)
+	all := new(bytes.Buffer)
+	logger := log.NewExtendedLogger(&log.Config{
+		Level: log.LEVEL_TRACE,
+		Sinks: []log.Sink{
+			{Level: log.LEVEL_ERROR, Encoder: log.JsonEncoder{}, Writer: alerts},
+			{Encoder: log.JsonEncoder{}, Writer: all},
+		},
+	})
+
+	logger.Trace("trace", nil)
+	logger.Warn("warn", nil)
+	logger.Error("error", nil)
+
+	if strings.Count(alerts.String(), "\n") != 1 || !strings.Contains(alerts.String(), `"level":"ERROR"`) {
+		t.Errorf("expected only the error in alerts, actual: %q", alerts.String())
+	}
+	if strings.Count(all.String(), "\n") != 3 {
+		t.Errorf("expected all records, actual: %q", all.String())
+	}
+}
+
+func TestLog_LevelsOutsideTheTwoLevelModelShouldKeepTheDefaultRule(t *testing.T) {
+	for _, level := range []string{log.LEVEL_TRACE, log.LEVEL_WARN, log.LEVEL_ERROR} {
+		output := new(bytes.Buffer)
+		sinkOutput := new(bytes.Buffer)
+		logger := log.NewLogger(&log.Config{
+			Level: level,
+			Sinks: []log.Sink{
+				{Encoder: log.TextEncoder{}, Writer: output},
+				{Level: level, Encoder: log.TextEncoder{}, Writer: sinkOutput},
+			},
+		})
+
+		logger.Debug("debug", nil)
+		logger.Info("info", nil)
+
+		for name, written := range map[string]string{"logger": output.String(), "sink": sinkOutput.String()} {
+			if !strings.Contains(written, "\tINFO\t") || strings.Contains(written, "\tDEBUG\t") {
+				t.Errorf("%s at %s: expected only INFO, actual: %q", name, level, written)
+			}
+		}
+		if !logger.Enabled(log.LEVEL_INFO) || logger.Enabled(log.LEVEL_DEBUG) {
+			t.Errorf("%s: expected INFO to be enabled and DEBUG to be disabled", level)
+		}
+	}
+}
@@ -1,30 +1,84 @@
 package log
 
 import (
-	"reflect"
 	"fmt"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"time"
 )
 
 const TIME_FORMAT = "2006-01-02T15:04:05.000000"
 const LEVEL_DEBUG = "DEBUG"
-const LEVEL_INFO  = "INFO"
+const LEVEL_INFO = "INFO"
+
+// Extended levels, valid only with Config.ExtendedLevels.
+const LEVEL_TRACE = "TRACE"
+const LEVEL_WARN = "WARN"
+const LEVEL_ERROR = "ERROR"
+const LEVEL_FATAL = "FATAL"
 
 type Config struct {
 	Level string
-	Formatter func(level string, message string, tags map[string]string, dateFormat string) string
-	Output func(formattedMessage string)
-	ProgramName string
+	// Formatter renders records, adapt function-style formatters like
+	// TextFormatter with FormatterFunc.
+	Formatter    Formatter
+	Output       func(formattedMessage string)
+	ProgramName  string
 	FunctionName string
-	DateFormat string
-	Tags map[string]string
+	DateFormat   string
+	Tags         Fields
+	// Encoder, when set, is used instead of Formatter. It renders records into
+	// pooled buffers and lets child loggers cache their rendered tags.
+	Encoder Encoder
+	// Writer, when set, is used instead of Output. Records are written
+	// directly from the pooled buffer, terminated by a newline. NewLogger
+	// adapts Output with OutputFunc if Writer is not set.
+	Writer io.Writer
+	// ErrorHandler, when set, receives write errors instead of the caller of
+	// Info and Debug.
+	ErrorHandler func(error)
+	// AtomicLevel is shared by a logger and all of its child loggers. NewLogger
+	// creates it from Level if it is not set.
+	AtomicLevel *AtomicLevel
+	// Sinks, when set, are used instead of Formatter, Encoder and Writer. Every
+	// record is written to all sinks whose level accepts it, a failing sink
+	// does not keep the record from the others.
+	Sinks []Sink
+	// Clock returns the time of a record, time.Now if it is not set. It is
+	// called once per record and the time is passed to every sink.
+	Clock func() time.Time
+	// Location is the time zone of the record times, UTC if it is not set.
+	Location *time.Location
+	// CaptureCaller adds the file, line and function of the code that called
+	// Info or Debug as caller tag. Wrappers of a logger set CallerSkip to the
+	// number of their own frames between the caller and the logger.
+	CaptureCaller bool
+	CallerSkip    int
+	// CaptureStack adds the stack of the call as stack tag if the context is an
+	// error or has an error tag.
+	CaptureStack bool
+	// Redactor, when set, masks sensitive tags of the logger and of every
+	// call before they are formatted.
+	Redactor *Redactor
+	// Hooks are run in order on every record that is written, before it is
+	// formatted. Child loggers run the hooks of their parent.
+	Hooks []Hook
+	// ExtendedLevels adds TRACE, WARN, ERROR and FATAL to INFO and DEBUG.
+	// Levels are ordered by severity and messages below the level of a logger
+	// are dropped, including INFO messages at WARN and above. The methods of
+	// ExtendedLogger return InvalidLevel without it.
+	ExtendedLevels bool
 }
 
 type LogFormattingFailed string
+
 func (err LogFormattingFailed) String() string {
 	return "LogFormattingFailed"
 }
 
 type InvalidContext string
+
 func (err InvalidContext) Error() string {
 	return "invalid type for context. Must be map, struct, ptr(map) or ptr(struct)"
 }
@@ -32,80 +86,414 @@ func (err InvalidContext) Error() string {
 type Logger interface {
 	Info(string, interface{}) error
 	Debug(string, interface{}) error
+	// DebugFunc calls the function for the message and context only if debug
+	// messages are enabled.
+	DebugFunc(func() (string, interface{})) error
+	// Enabled reports whether messages of the given level are written.
+	Enabled(string) bool
 	ChildLogger(string, interface{}) (Logger, error)
 }
 
+// ExtendedLogger is the Logger of the extended levels. Loggers created with
+// Config.ExtendedLevels implement it, including their child loggers. Fatal
+// writes the message like the other levels and does not exit the program.
+type ExtendedLogger interface {
+	Logger
+	Trace(string, interface{}) error
+	Warn(string, interface{}) error
+	Error(string, interface{}) error
+	Fatal(string, interface{}) error
+}
+
 type Log struct {
-	config *Config
+	config     *Config
+	functions  []string
+	sinks      []logSink
+	encoders   []sinkEncoder
+	formatters []Formatter
+	sequence   *uint64 // shared with the child loggers
 }
+
 func (log Log) Info(message string, tags interface{}) error {
-	mergedTags, err := mergeTags(log.config.Tags, tags)
-	if err != nil {
-		return err
+	if log.Enabled(LEVEL_INFO) {
+		return log.write(LEVEL_INFO, message, tags)
 	}
-	log.config.Output(log.config.Formatter(LEVEL_INFO, message, mergedTags, log.config.DateFormat))
 	return nil
 }
 
 func (log Log) Debug(message string, tags interface{}) error {
-	if log.config.Level == LEVEL_DEBUG {
-		mergedTags, err := mergeTags(log.config.Tags, tags)
-		if err != nil {
-			return err
-		}
-		log.config.Output(log.config.Formatter(LEVEL_DEBUG, message, mergedTags, log.config.DateFormat))
+	if log.Enabled(LEVEL_DEBUG) {
+		return log.write(LEVEL_DEBUG, message, tags)
+	}
+	return nil
+}
+
+func (log Log) Trace(message string, tags interface{}) error {
+	if !log.config.AtomicLevel.Extended() {
+		return InvalidLevel(LEVEL_TRACE)
+	}
+	if log.Enabled(LEVEL_TRACE) {
+		return log.write(LEVEL_TRACE, message, tags)
+	}
+	return nil
+}
+
+func (log Log) Warn(message string, tags interface{}) error {
+	if !log.config.AtomicLevel.Extended() {
+		return InvalidLevel(LEVEL_WARN)
+	}
+	if log.Enabled(LEVEL_WARN) {
+		return log.write(LEVEL_WARN, message, tags)
+	}
+	return nil
+}
+
+func (log Log) Error(message string, tags interface{}) error {
+	if !log.config.AtomicLevel.Extended() {
+		return InvalidLevel(LEVEL_ERROR)
+	}
+	if log.Enabled(LEVEL_ERROR) {
+		return log.write(LEVEL_ERROR, message, tags)
+	}
+	return nil
+}
+
+func (log Log) Fatal(message string, tags interface{}) error {
+	if !log.config.AtomicLevel.Extended() {
+		return InvalidLevel(LEVEL_FATAL)
+	}
+	if log.Enabled(LEVEL_FATAL) {
+		return log.write(LEVEL_FATAL, message, tags)
+	}
+	return nil
+}
+
+func (log Log) DebugFunc(messageFunc func() (string, interface{})) error {
+	if log.Enabled(LEVEL_DEBUG) {
+		message, tags := messageFunc()
+		return log.write(LEVEL_DEBUG, message, tags)
 	}
 	return nil
 }
 
+func (log Log) Enabled(level string) bool {
+	extended := log.config.AtomicLevel.Extended()
+	if !log.config.AtomicLevel.isValid(level) || !levelEnabled(log.config.AtomicLevel.levelFor(log.functions), level, extended) {
+		return false
+	}
+	for _, sink := range log.sinks {
+		if sink.enabled(level, extended) {
+			return true
+		}
+	}
+	return false
+}
+
+// write formats the record once per distinct encoder or formatter and writes
+// it to every sink that accepts the level.
+func (log Log) write(level string, message string, tags interface{}) error {
+	fields := getFieldBuffer()
+	defer putFieldBuffer(fields)
+	if err := fields.appendContext(tags); err != nil {
+		return err
+	}
+	var caller Caller
+	var stack string
+	if log.config.CaptureCaller || log.config.CaptureStack {
+		caller, stack = log.capture(fields)
+	}
+	fields.sortUnique()
+	if log.config.Redactor != nil {
+		log.config.Redactor.redact(fields.fields)
+	}
+
+	var errs error
+	var record *Record
+	extended := log.config.AtomicLevel.Extended()
+	now := log.now()
+	sequence := atomic.AddUint64(log.sequence, 1)
+	if len(log.config.Hooks) > 0 {
+		record = log.mergedRecord(now, sequence, level, message, fields.fields, caller, stack)
+		if !log.runHooks(record) {
+			return nil
+		}
+		level = record.Level
+	}
+	for i, cached := range log.encoders {
+		var buffer *[]byte
+		for _, sink := range log.sinks {
+			if sink.encoder != i || !sink.enabled(level, extended) {
+				continue
+			}
+			if buffer == nil {
+				buffer = getBuffer()
+				if record != nil { // changed by hooks, the cached tags may be stale
+					*buffer = appendWithEncoder(*buffer, cached.encoder, record)
+				} else {
+					*buffer = cached.encoder.AppendHeader(*buffer, now, log.config.DateFormat, level, message, log.recordFunction(fields.fields))
+					*buffer = cached.rendered.appendMerged(*buffer, cached.encoder, fields.fields)
+					*buffer = cached.encoder.AppendFooter(*buffer)
+				}
+				*buffer = append(*buffer, '\n')
+			}
+			errs = joinErrors(errs, log.writeRecord(sink.Writer, *buffer))
+		}
+		if buffer != nil {
+			putBuffer(buffer)
+		}
+	}
+
+	for i, formatter := range log.formatters {
+		var buffer *[]byte
+		for _, sink := range log.sinks {
+			if sink.formatter != i || !sink.enabled(level, extended) {
+				continue
+			}
+			if record == nil {
+				record = log.mergedRecord(now, sequence, level, message, fields.fields, caller, stack)
+			}
+			if buffer == nil {
+				buffer = getBuffer()
+				*buffer = formatter.Format(*buffer, record)
+				*buffer = append(*buffer, '\n')
+			}
+			errs = joinErrors(errs, log.writeRecord(sink.Writer, *buffer))
+		}
+		if buffer != nil {
+			putBuffer(buffer)
+		}
+	}
+	return errs
+}
+
+// mergedRecord returns the record of a call with the tags of the logger merged
+// with the fields of the call.
+func (log Log) mergedRecord(now time.Time, sequence uint64, level string, message string, fields Fields, caller Caller, stack string) *Record {
+	mergedTags, _ := mergeTags(log.config.Tags, fields)
+	record := &Record{
+		Time:       now,
+		Level:      level,
+		Message:    message,
+		Program:    log.config.ProgramName,
+		Function:   log.recordFunction(fields),
+		Fields:     mergedTags,
+		Caller:     caller,
+		Stack:      stack,
+		Sequence:   sequence,
+		DateFormat: log.config.DateFormat,
+	}
+	if errValue, ok := mergedTags.Get("error"); ok && errValue.Kind() == KindError {
+		record.Error = errValue.Err()
+	}
+	return record
+}
+
+// recordFunction returns the function of a record. A function tag of the call
+// overrides the function of the logger, like it overrides the function tag.
+func (log Log) recordFunction(fields Fields) string {
+	if function, found := fields.Get("function"); found {
+		return function.String()
+	}
+	return log.config.FunctionName
+}
+
+func (log Log) now() time.Time {
+	now := time.Now
+	if log.config.Clock != nil {
+		now = log.config.Clock
+	}
+	if log.config.Location != nil {
+		return now().In(log.config.Location)
+	}
+	return now().UTC()
+}
+
+// writeRecord writes a record terminated by a newline with a single call to
+// Write. Failures are returned as OutputFailed or passed to the ErrorHandler.
+func (log Log) writeRecord(writer io.Writer, record []byte) error {
+	written, err := writer.Write(record)
+	if err == nil && written < len(record) {
+		err = io.ErrShortWrite
+	}
+	if err == nil {
+		return nil
+	}
+	outputErr := &OutputFailed{err}
+	if log.config.ErrorHandler != nil {
+		log.config.ErrorHandler(outputErr)
+		return nil
+	}
+	return outputErr
+}
+
 func (log Log) ChildLogger(functionName string, context interface{}) (Logger, error) {
 	childConfig := new(Config)
-	childConfig.Level = log.config.Level
-	childConfig.Formatter = log.config.Formatter
-	childConfig.Output = log.config.Output
-	childConfig.DateFormat = log.config.DateFormat
-	childConfig.ProgramName = log.config.ProgramName
-	childConfig.Tags = make(map[string]string, len(log.config.Tags))
-	for k, v := range log.config.Tags {
-		childConfig.Tags[k] = v
-	}
+	*childConfig = *log.config
 	childConfig.FunctionName = functionName
-	mergedTags, err := mergeTags(childConfig.Tags, context)
+	mergedTags, err := mergeTags(log.config.Tags, context)
 	if err != nil {
 		return nil, err
 	}
 	childConfig.Tags = mergedTags
-	return NewLogger(childConfig), nil
+	child := newLog(childConfig)
+	child.sequence = log.sequence
+	child.functions = make([]string, len(log.functions), len(log.functions)+1)
+	copy(child.functions, log.functions)
+	child.functions = append(child.functions, functionName)
+	childConfig.AtomicLevel.registerFunction(functionName)
+	return child, nil
+}
+
+// NewExtendedLogger creates a logger with Config.ExtendedLevels.
+func NewExtendedLogger(config *Config) ExtendedLogger {
+	config.ExtendedLevels = true
+	return NewLogger(config).(ExtendedLogger)
 }
 
 func NewLogger(config *Config) Logger {
-	if config.Tags == nil {
-		config.Tags = map[string]string{}
+	if config.AtomicLevel == nil {
+		config.AtomicLevel = NewAtomicLevel(config.Level)
+	}
+	if config.ExtendedLevels {
+		config.AtomicLevel.EnableExtendedLevels()
+	}
+	if config.Writer == nil && config.Output != nil {
+		config.Writer = OutputFunc(config.Output)
+	}
+	logger := newLog(config)
+	logger.functions = []string{config.FunctionName}
+	logger.sequence = new(uint64)
+	config.AtomicLevel.registerFunction(config.FunctionName)
+	return logger
+}
+
+func newLog(config *Config) *Log {
+	config.Tags, _ = mergeTags(config.Tags, Fields{
+		String("program", config.ProgramName),
+		String("function", config.FunctionName),
+	})
+	if config.Redactor != nil {
+		config.Redactor.redact(config.Tags)
 	}
-	config.Tags["program"] = config.ProgramName
-	config.Tags["function"] = config.FunctionName
 	logger := new(Log)
 	logger.config = config
+	logger.sinks, logger.encoders, logger.formatters = newSinks(config)
 	return logger
 }
 
-func mergeTags(tags map[string]string, context interface{}) (map[string]string, error) {
-	outputTags := map[string]string{}
-	for name, value := range tags {
-		outputTags[name] = value
+// renderedFields holds the tags of a logger rendered by its encoder, sorted
+// by key, so that they are encoded once instead of on every call.
+type renderedFields struct {
+	data     []byte
+	segments []renderedField
+}
+
+type renderedField struct {
+	key string
+	end int
+}
+
+func renderFields(encoder Encoder, fields Fields) renderedFields {
+	sortedFields := fields.sorted()
+	rendered := renderedFields{segments: make([]renderedField, len(sortedFields))}
+	for i, field := range sortedFields {
+		rendered.data = encoder.AppendField(rendered.data, field)
+		rendered.segments[i] = renderedField{field.Key, len(rendered.data)}
 	}
+	return rendered
+}
+
+// appendMerged appends the cached tags merged with fields, which must be
+// sorted by key and unique. Fields replace cached tags with the same key.
+func (rendered renderedFields) appendMerged(dst []byte, encoder Encoder, fields Fields) []byte {
+	start, i, j := 0, 0, 0
+	for i < len(rendered.segments) || j < len(fields) {
+		switch {
+		case j == len(fields) || (i < len(rendered.segments) && rendered.segments[i].key < fields[j].Key):
+			dst = append(dst, rendered.data[start:rendered.segments[i].end]...)
+			start = rendered.segments[i].end
+			i++
+		case i == len(rendered.segments) || fields[j].Key < rendered.segments[i].key:
+			dst = encoder.AppendField(dst, fields[j])
+			j++
+		default:
+			dst = encoder.AppendField(dst, fields[j])
+			start = rendered.segments[i].end
+			i++
+			j++
+		}
+	}
+	return dst
+}
+
+// appendContext adds the tags of a context to the buffer. The common context
+// types are handled without reflection or allocations.
+func (buffer *fieldBuffer) appendContext(context interface{}) error {
+	switch aTags := context.(type) {
+	case nil:
+	case Field:
+		buffer.fields = append(buffer.fields, aTags)
+	case Fields:
+		buffer.fields = append(buffer.fields, aTags...)
+	case Context:
+		return buffer.appendContextItems(aTags)
+	case LogMarshaler:
+		if !isNilPointer(aTags) {
+			buffer.fields = append(buffer.fields, aTags.MarshalLog()...)
+		}
+	case map[string]string:
+		for name, value := range aTags {
+			buffer.fields = append(buffer.fields, String(name, value))
+		}
+	case map[string]interface{}:
+		for name, value := range aTags {
+			buffer.fields = append(buffer.fields, Any(name, value))
+		}
+	default:
+		mergedTags, err := mergeTags(nil, context)
+		if err != nil {
+			return err
+		}
+		buffer.fields = append(buffer.fields, mergedTags...)
+	}
+	return nil
+}
+
+func mergeTags(tags Fields, context interface{}) (Fields, error) {
+	outputTags := make(Fields, len(tags))
+	copy(outputTags, tags)
 
 	if context == nil {
 		return outputTags, nil
 	}
 
 	switch aTags := context.(type) {
-	case error:
-		outputTags["error"] = aTags.Error()
+	case Field:
+		return outputTags.set(aTags), nil
+	case Fields:
+		for _, field := range aTags {
+			outputTags = outputTags.set(field)
+		}
 		return outputTags, nil
-	case fmt.Stringer:
-		outputTags["context"] = aTags.String()
+	case Context:
+		return mergeContextItems(outputTags, aTags)
+	case LogMarshaler:
+		if isNilPointer(aTags) {
+			return outputTags, nil
+		}
+		for _, field := range aTags.MarshalLog() {
+			outputTags = outputTags.set(field)
+		}
+		return outputTags, nil
+	case map[string]interface{}:
+		for name, value := range aTags {
+			outputTags = outputTags.set(Any(name, value))
+		}
 		return outputTags, nil
+	case error:
+		return outputTags.set(Err("error", aTags)), nil
+	case fmt.Stringer:
+		return outputTags.set(String("context", aTags.String())), nil
 	}
 
 	reflectedValue := reflect.ValueOf(context)
@@ -118,25 +506,11 @@ func mergeTags(tags map[string]string, context interface{}) (map[string]string,
 	}
 
 	if reflectedValue.Kind() == reflect.Map {
-		for _, name := range reflectedValue.MapKeys() {
-			outputTags[name.String()] = reflectedValue.MapIndex(name).String()
+		for _, field := range mapFields(reflectedValue) {
+			outputTags = outputTags.set(field)
 		}
 	} else if reflectedValue.Kind() == reflect.Struct {
-		reflectedContext := reflect.TypeOf(context)
-		for {
-			if reflectedContext.Kind() == reflect.Ptr {
-				reflectedContext = reflectedContext.Elem()
-			} else {
-				break
-			}
-		}
-
-		for i := 0; i < reflectedContext.NumField(); i++ {
-			currentField := reflectedContext.Field(i)
-			if currentField.PkgPath == "" {  // merge only exported fields
-				outputTags[currentField.Name] = reflectedValue.FieldByName(currentField.Name).String()
-			}
-		}
+		outputTags = structTags(outputTags, reflectedValue, "", 0)
 	} else {
 		return nil, new(InvalidContext)
 	}
@@ -1,21 +1,28 @@
 package log_test
 
 import (
+	"bytes"
+	"io/ioutil"
 	"testing"
+	"time"
+
 	"github.com/flowpl/log"
 )
 
 const FORMATTED_MESSAGE = "message returned from dummyFormatter"
 
 type DummyFormatOutput struct {
-	level string
-	message string
-	tags map[string]string
-	dateFormat string
+	time          time.Time
+	level         string
+	message       string
+	tags          log.Fields
+	dateFormat    string
 	outputMessage string
 }
-func (dfo *DummyFormatOutput) createDummyFormatter() func(string, string, map[string]string, string) string{
-	return func(level string, message string, tags map[string]string, dateFormat string) string {
+
+func (dfo *DummyFormatOutput) createDummyFormatter() log.FormatterFunc {
+	return func(t time.Time, level string, message string, tags log.Fields, dateFormat string) string {
+		dfo.time = t
 		dfo.level = level
 		dfo.message = message
 		dfo.tags = tags
@@ -23,8 +30,12 @@ func (dfo *DummyFormatOutput) createDummyFormatter() func(string, string, map[st
 		return FORMATTED_MESSAGE
 	}
 }
+func (dfo *DummyFormatOutput) tag(key string) string {
+	value, _ := dfo.tags.Get(key)
+	return value.String()
+}
 func (dfo *DummyFormatOutput) createDummyOutput() func(string) {
-	return func (message string) {
+	return func(message string) {
 		dfo.outputMessage = message
 	}
 }
@@ -32,13 +43,13 @@ func (dfo *DummyFormatOutput) createDummyOutput() func(string) {
 func TestNewLoggerCreatesANewLogThatUsesGivenConfig(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_DEBUG,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		map[string]string{"tag1":"value1"},
+		Level:        log.LEVEL_DEBUG,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
+		Tags:         log.Fields{log.String("tag1", "value1")},
 	}
 	var result log.Logger = log.NewLogger(config)
 
@@ -51,16 +62,16 @@ func TestNewLoggerCreatesANewLogThatUsesGivenConfig(t *testing.T) {
 		t.Errorf("expected message to be \"%s\", actual: \"%s\"", "message", dfo.message)
 	}
 
-	if dfo.tags["tag1"] != "value1" {
-		t.Errorf("expected tag \"tag1\" to be \"value1\", actual: \"%s\"", dfo.tags["tag1"])
+	if dfo.tag("tag1") != "value1" {
+		t.Errorf("expected tag \"tag1\" to be \"value1\", actual: \"%s\"", dfo.tag("tag1"))
 	}
 
-	if dfo.tags["function"] != "main" {
-		t.Errorf("expected tag \"function\" to be \"main\", actual: \"%s\"", dfo.tags["function"])
+	if dfo.tag("function") != "main" {
+		t.Errorf("expected tag \"function\" to be \"main\", actual: \"%s\"", dfo.tag("function"))
 	}
 
-	if dfo.tags["program"] != "log_test" {
-		t.Errorf("expected tag \"program\" to be \"log_test\", actual: \"%s\"", dfo.tags["program"])
+	if dfo.tag("program") != "log_test" {
+		t.Errorf("expected tag \"program\" to be \"log_test\", actual: \"%s\"", dfo.tag("program"))
 	}
 
 	if len(dfo.tags) != 3 {
@@ -79,13 +90,12 @@ func TestNewLoggerCreatesANewLogThatUsesGivenConfig(t *testing.T) {
 func TestNewLoggerShouldAcceptNilAsTagsConfig(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_DEBUG,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_DEBUG,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	result := log.NewLogger(config)
 	result.Debug("message", map[string]string{})
@@ -98,13 +108,12 @@ func TestNewLoggerShouldAcceptNilAsTagsConfig(t *testing.T) {
 func TestLog_InfoShouldOutputMessagesIfLevelIsDebug(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_DEBUG,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_DEBUG,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	result := log.NewLogger(config)
 	result.Info("message", map[string]string{})
@@ -121,13 +130,12 @@ func TestLog_InfoShouldOutputMessagesIfLevelIsDebug(t *testing.T) {
 func TestLog_InfoShouldOutputMessagesIfLevelIsInfo(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	result := log.NewLogger(config)
 	result.Info("message", map[string]string{})
@@ -144,13 +152,12 @@ func TestLog_InfoShouldOutputMessagesIfLevelIsInfo(t *testing.T) {
 func TestLog_InfoShouldReturnInvalidContext(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	result := log.NewLogger(config)
 	err := result.Info("message", "")
@@ -163,13 +170,12 @@ func TestLog_InfoShouldReturnInvalidContext(t *testing.T) {
 func TestLog_DebugShouldOutputMessagesIfLevelIsDebug(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_DEBUG,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_DEBUG,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	result := log.NewLogger(config)
 	result.Debug("message", map[string]string{})
@@ -186,13 +192,12 @@ func TestLog_DebugShouldOutputMessagesIfLevelIsDebug(t *testing.T) {
 func TestLog_DebugShouldNotOutputMessagesIfLevelIsInfo(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	result := log.NewLogger(config)
 	result.Debug("message", map[string]string{})
@@ -209,13 +214,12 @@ func TestLog_DebugShouldNotOutputMessagesIfLevelIsInfo(t *testing.T) {
 func TestLog_DebugShouldReturnInvalidContext(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_DEBUG,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_DEBUG,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	result := log.NewLogger(config)
 	err := result.Debug("message", "")
@@ -228,13 +232,12 @@ func TestLog_DebugShouldReturnInvalidContext(t *testing.T) {
 func TestLog_ChildLoggerShouldCreateANewLoggerInstance(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	parentLogger := log.NewLogger(config)
 	result, _ := parentLogger.ChildLogger("child_test", map[string]string{})
@@ -248,61 +251,58 @@ func TestLog_ChildLoggerShouldCreateANewLoggerInstance(t *testing.T) {
 func TestLog_ChildLoggerShouldMergeItsContextWithParentLoggersTags(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	parentLogger := log.NewLogger(config)
-	result, _ := parentLogger.ChildLogger("child_test", map[string]string{"child_tag":"value"})
+	result, _ := parentLogger.ChildLogger("child_test", map[string]string{"child_tag": "value"})
 
 	result.Info("message", map[string]string{})
 	if len(dfo.tags) != 3 {
 		t.Errorf("expected exactly 3 tags, actual: \"%d\"", len(dfo.tags))
 	}
 
-	if dfo.tags["child_tag"] != "value" {
-		t.Errorf("expected tag child_tag to be \"%s\", actual: \"%s\"", "message", dfo.tags["child_tag"])
+	if dfo.tag("child_tag") != "value" {
+		t.Errorf("expected tag child_tag to be \"%s\", actual: \"%s\"", "message", dfo.tag("child_tag"))
 	}
 }
 
 func TestLog_ChildLoggerShouldSetFunctionNameOnTheChildLoggerButLeaveTheParentTagsUnchanged(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	parentLogger := log.NewLogger(config)
 	result, _ := parentLogger.ChildLogger("child_test", map[string]string{})
 
 	result.Info("child", map[string]string{})
-	if dfo.tags["function"] != "child_test" {
-		t.Errorf("expected child logger function tag to be \"child_test\", actual: \"%s\"", dfo.tags["function"])
+	if dfo.tag("function") != "child_test" {
+		t.Errorf("expected child logger function tag to be \"child_test\", actual: \"%s\"", dfo.tag("function"))
 	}
 	parentLogger.Info("parent", map[string]string{})
-	if dfo.tags["function"] != "main" {
-		t.Errorf("expected parent logger function tag to be \"main\", actual: \"%s\"", dfo.tags["function"])
+	if dfo.tag("function") != "main" {
+		t.Errorf("expected parent logger function tag to be \"main\", actual: \"%s\"", dfo.tag("function"))
 	}
 }
 
 func TestLog_ChildLoggerShouldAllowNilAsContext(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	parentLogger := log.NewLogger(config)
 	result, _ := parentLogger.ChildLogger("child_test", nil)
@@ -316,18 +316,18 @@ func TestLog_ChildLoggerShouldAllowNilAsContext(t *testing.T) {
 
 type arbitraryStruct struct {
 	ExportedStructTag string
-	structTag string
+	structTag         string
 }
+
 func TestLog_ChildLoggerShouldAcceptAnArbitraryStructAsContextAndMergeExportedFieldsIntoTags(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	parentLogger := log.NewLogger(config)
 	result, _ := parentLogger.ChildLogger("child_test", arbitraryStruct{"exportedValue", "value"})
@@ -337,21 +337,20 @@ func TestLog_ChildLoggerShouldAcceptAnArbitraryStructAsContextAndMergeExportedFi
 	if len(dfo.tags) != 3 {
 		t.Errorf("expected exactly 3 tags, actual: \"%d\"", len(dfo.tags))
 	}
-	if dfo.tags["ExportedStructTag"] != "exportedValue" {
-		t.Errorf("expected tag ExportedStructTag to be \"exportedValue\", actual: \"%s\"", dfo.tags["ExportedStructTag"])
+	if dfo.tag("ExportedStructTag") != "exportedValue" {
+		t.Errorf("expected tag ExportedStructTag to be \"exportedValue\", actual: \"%s\"", dfo.tag("ExportedStructTag"))
 	}
 }
 
 func TestLog_ChildLoggerShouldAcceptAPtrToArbitraryStructAsContextAndMergeExportedFieldsIntoTags(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	parentLogger := log.NewLogger(config)
 	result, _ := parentLogger.ChildLogger("child_test", &arbitraryStruct{"exportedValue", "value"})
@@ -361,80 +360,78 @@ func TestLog_ChildLoggerShouldAcceptAPtrToArbitraryStructAsContextAndMergeExport
 	if len(dfo.tags) != 3 {
 		t.Errorf("expected exactly 3 tags, actual: \"%d\"", len(dfo.tags))
 	}
-	if dfo.tags["ExportedStructTag"] != "exportedValue" {
-		t.Errorf("expected tag ExportedStructTag to be \"exportedValue\", actual: \"%s\"", dfo.tags["ExportedStructTag"])
+	if dfo.tag("ExportedStructTag") != "exportedValue" {
+		t.Errorf("expected tag ExportedStructTag to be \"exportedValue\", actual: \"%s\"", dfo.tag("ExportedStructTag"))
 	}
 }
 
-
 type arbitraryStringer struct {
 	ExportedStructTag string
-	structTag string
+	structTag         string
 }
+
 func (as arbitraryStringer) String() string {
 	return "result from stringer"
 }
 func TestLog_ChildLoggerShouldUseTheStringerInterfaceOfContextIfPresent(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	parentLogger := log.NewLogger(config)
 	result, _ := parentLogger.ChildLogger("child_test", arbitraryStringer{"exportedValue", "value"})
 
 	result.Info("message", map[string]string{})
 	if len(dfo.tags) != 3 {
-		t.Errorf("expected exactly 3 tags, actual: \"%s\"", len(dfo.tags))
+		t.Errorf("expected exactly 3 tags, actual: \"%d\"", len(dfo.tags))
 	}
 
-	if dfo.tags["context"] != "result from stringer" {
-		t.Errorf("expected tag context to be \"%s\", actual: \"%s\"", "result from stringer", dfo.tags["context"])
+	if dfo.tag("context") != "result from stringer" {
+		t.Errorf("expected tag context to be \"%s\", actual: \"%s\"", "result from stringer", dfo.tag("context"))
 	}
 }
 
-
 type arbitraryError struct {
 	ExportedStructTag string
-	structTag string
+	structTag         string
 }
+
 func (as arbitraryError) Error() string {
 	return "result from error"
 }
 func TestLog_ChildLoggerShouldUseTheErrorInterfaceOfContextIfPresent(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	parentLogger := log.NewLogger(config)
 	result, _ := parentLogger.ChildLogger("child_test", arbitraryError{"exportedValue", "value"})
 
 	result.Info("message", map[string]string{})
 	if len(dfo.tags) != 3 {
-		t.Errorf("expected exactly 3 tags, actual: \"%s\"", len(dfo.tags))
+		t.Errorf("expected exactly 3 tags, actual: \"%d\"", len(dfo.tags))
 	}
 
-	if dfo.tags["error"] != "result from error" {
-		t.Errorf("expected tag context to be \"%s\", actual: \"%s\"", "result from error", dfo.tags["error"])
+	if dfo.tag("error") != "result from error" {
+		t.Errorf("expected tag context to be \"%s\", actual: \"%s\"", "result from error", dfo.tag("error"))
 	}
 }
 
-
 type arbitraryErrorStringer struct {
 	ExportedStructTag string
-	structTag string
+	structTag         string
 }
+
 func (as arbitraryErrorStringer) Error() string {
 	return "result from error"
 }
@@ -444,38 +441,35 @@ func (as arbitraryErrorStringer) String() string {
 func TestLog_ChildLoggerShouldPreferErrorOverStringer(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	parentLogger := log.NewLogger(config)
 	result, _ := parentLogger.ChildLogger("child_test", arbitraryErrorStringer{"exportedValue", "value"})
 
 	result.Info("message", map[string]string{})
 	if len(dfo.tags) != 3 {
-		t.Errorf("expected exactly 3 tags, actual: \"%s\"", len(dfo.tags))
+		t.Errorf("expected exactly 3 tags, actual: \"%d\"", len(dfo.tags))
 	}
 
-	if dfo.tags["error"] != "result from error" {
-		t.Errorf("expected tag context to be \"%s\", actual: \"%s\"", "result from error", dfo.tags["error"])
+	if dfo.tag("error") != "result from error" {
+		t.Errorf("expected tag context to be \"%s\", actual: \"%s\"", "result from error", dfo.tag("error"))
 	}
 }
 
-
 func TestLog_ChildLoggerShouldReturnInvalidContextOnInvalidContext(t *testing.T) {
 	dfo := new(DummyFormatOutput)
 	config := &log.Config{
-		log.LEVEL_INFO,
-		dfo.createDummyFormatter(),
-		dfo.createDummyOutput(),
-		"log_test",
-		"main",
-		"2006",
-		nil,
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
 	}
 	parentLogger := log.NewLogger(config)
 	_, err := parentLogger.ChildLogger("child_test", "")
@@ -484,3 +478,310 @@ func TestLog_ChildLoggerShouldReturnInvalidContextOnInvalidContext(t *testing.T)
 		t.Error("expected InvalidContext error")
 	}
 }
+
+func TestLog_InfoShouldAcceptFieldsAsContext(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	config := &log.Config{
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
+	}
+	logger := log.NewLogger(config)
+	logger.Info("message", log.Fields{log.Int("count", 3), log.Bool("ok", true)})
+
+	count, _ := dfo.tags.Get("count")
+	if count.Kind() != log.KindInt64 || count.Int64() != 3 {
+		t.Errorf("expected tag count to be int64 3, actual: %s %q", count.Kind(), count)
+	}
+
+	ok, _ := dfo.tags.Get("ok")
+	if ok.Kind() != log.KindBool || !ok.Bool() {
+		t.Errorf("expected tag ok to be bool true, actual: %s %q", ok.Kind(), ok)
+	}
+}
+
+func TestLog_InfoShouldKeepMapValueTypes(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	config := &log.Config{
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
+	}
+	logger := log.NewLogger(config)
+	logger.Info("message", map[string]interface{}{"count": 3, "ratio": 0.5})
+
+	count, _ := dfo.tags.Get("count")
+	if count.Kind() != log.KindInt64 || count.Int64() != 3 {
+		t.Errorf("expected tag count to be int64 3, actual: %s %q", count.Kind(), count)
+	}
+
+	ratio, _ := dfo.tags.Get("ratio")
+	if ratio.Kind() != log.KindFloat64 || ratio.Float64() != 0.5 {
+		t.Errorf("expected tag ratio to be float64 0.5, actual: %s %q", ratio.Kind(), ratio)
+	}
+}
+
+func TestLog_ChildLoggerShouldKeepErrorContextTyped(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	config := &log.Config{
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
+	}
+	parentLogger := log.NewLogger(config)
+	result, _ := parentLogger.ChildLogger("child_test", arbitraryError{"exportedValue", "value"})
+
+	result.Info("message", nil)
+	errorTag, _ := dfo.tags.Get("error")
+	if errorTag.Kind() != log.KindError {
+		t.Errorf("expected tag error to be of kind error, actual: %s", errorTag.Kind())
+	}
+}
+
+func TestLog_InfoShouldUseTheEncoderAndWriterIfConfigured(t *testing.T) {
+	output := new(bytes.Buffer)
+	config := &log.Config{
+		Level:        log.LEVEL_INFO,
+		Encoder:      log.TextEncoder{},
+		Writer:       output,
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
+		Tags:         log.Fields{log.String("b", "parent"), log.String("d", "parent")},
+		Clock:        func() time.Time { return time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC) },
+	}
+	parentLogger := log.NewLogger(config)
+	logger, _ := parentLogger.ChildLogger("child_test", log.Fields{log.Int("c", 3)})
+	logger.Info("message", map[string]string{"a": "call", "d": "call"})
+
+	expected := "2017\tINFO\tchild_test\tmessage\ta:call,b:parent,c:3,d:call,program:log_test\n"
+	if output.String() != expected {
+		t.Errorf("expected output to be %q, actual: %q", expected, output.String())
+	}
+}
+
+func TestLog_InfoShouldRenderTheSameJsonWithEncoderAndFormatter(t *testing.T) {
+	encoded := new(bytes.Buffer)
+	formatted := new(bytes.Buffer)
+	tags := log.Fields{log.String("message", "tag"), log.Object("nested", log.Int("a", 1))}
+	encoderLogger := log.NewLogger(&log.Config{Encoder: log.JsonEncoder{}, Writer: encoded, DateFormat: "2006", Tags: tags})
+	formatterLogger := log.NewLogger(&log.Config{Formatter: log.FormatterFunc(log.JsonFormatter), Writer: formatted, DateFormat: "2006", Tags: tags})
+
+	encoderLogger.Info("message", log.Fields{log.Bool("ok", true)})
+	formatterLogger.Info("message", log.Fields{log.Bool("ok", true)})
+	if encoded.String() != formatted.String() {
+		t.Errorf("expected encoder output %q to equal formatter output %q", encoded.String(), formatted.String())
+	}
+}
+
+func TestLog_InfoWithEncoderShouldNotAllocate(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items randomly under the race detector")
+	}
+	logger := newBenchmarkLogger(log.JsonEncoder{})
+	context := map[string]string{"request": "abc"}
+
+	allocations := testing.AllocsPerRun(100, func() {
+		logger.Info("message", nil)
+		logger.Info("message", context)
+	})
+	if allocations != 0 {
+		t.Errorf("expected no allocations, actual: %.1f", allocations)
+	}
+}
+
+func newBenchmarkLogger(encoder log.Encoder) log.Logger {
+	config := &log.Config{
+		Level:        log.LEVEL_INFO,
+		Encoder:      encoder,
+		Writer:       ioutil.Discard,
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   log.TIME_FORMAT,
+		Tags:         log.Fields{log.String("service", "benchmark"), log.Int("version", 3)},
+	}
+	logger, _ := log.NewLogger(config).ChildLogger("handler", log.Fields{log.String("path", "/orders")})
+	return logger
+}
+
+func BenchmarkLog_InfoTextEncoder(b *testing.B) {
+	logger := newBenchmarkLogger(log.TextEncoder{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("message", nil)
+	}
+}
+
+func BenchmarkLog_InfoJsonEncoder(b *testing.B) {
+	logger := newBenchmarkLogger(log.JsonEncoder{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("message", nil)
+	}
+}
+
+func BenchmarkLog_InfoJsonEncoderWithContext(b *testing.B) {
+	logger := newBenchmarkLogger(log.JsonEncoder{})
+	context := map[string]string{"request": "abc", "path": "/override"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("message", context)
+	}
+}
+
+func BenchmarkLog_InfoJsonFormatter(b *testing.B) {
+	config := &log.Config{
+		Level:        log.LEVEL_INFO,
+		Formatter:    log.FormatterFunc(log.JsonFormatter),
+		Writer:       ioutil.Discard,
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   log.TIME_FORMAT,
+		Tags:         log.Fields{log.String("service", "benchmark"), log.Int("version", 3)},
+	}
+	logger, _ := log.NewLogger(config).ChildLogger("handler", log.Fields{log.String("path", "/orders")})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		logger.Info("message", nil)
+	}
+}
+
+func TestLog_EnabledShouldReportDebugOnlyIfLevelIsDebug(t *testing.T) {
+	infoLogger := log.NewLogger(&log.Config{Level: log.LEVEL_INFO})
+	debugLogger := log.NewLogger(&log.Config{Level: log.LEVEL_DEBUG})
+
+	if !infoLogger.Enabled(log.LEVEL_INFO) || infoLogger.Enabled(log.LEVEL_DEBUG) {
+		t.Error("expected only INFO to be enabled if level is INFO")
+	}
+
+	if !debugLogger.Enabled(log.LEVEL_INFO) || !debugLogger.Enabled(log.LEVEL_DEBUG) {
+		t.Error("expected INFO and DEBUG to be enabled if level is DEBUG")
+	}
+}
+
+func TestLog_DebugFuncShouldNotCallTheFunctionIfLevelIsInfo(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	config := &log.Config{
+		Level:        log.LEVEL_INFO,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
+	}
+	logger := log.NewLogger(config)
+	called := false
+	logger.DebugFunc(func() (string, interface{}) {
+		called = true
+		return "message", nil
+	})
+
+	if called {
+		t.Error("expected message function not to be called")
+	}
+
+	if dfo.outputMessage != "" {
+		t.Errorf("expected outputMessage to be [empty string], actual \"%s\"", dfo.outputMessage)
+	}
+}
+
+func TestLog_DebugFuncShouldOutputTheMessageAndContextIfLevelIsDebug(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	config := &log.Config{
+		Level:        log.LEVEL_DEBUG,
+		Formatter:    dfo.createDummyFormatter(),
+		Output:       dfo.createDummyOutput(),
+		ProgramName:  "log_test",
+		FunctionName: "main",
+		DateFormat:   "2006",
+	}
+	logger := log.NewLogger(config)
+	logger.DebugFunc(func() (string, interface{}) {
+		return "lazy message", map[string]string{"lazy": "value"}
+	})
+
+	if dfo.level != log.LEVEL_DEBUG || dfo.message != "lazy message" {
+		t.Errorf("expected DEBUG \"lazy message\", actual: %s \"%s\"", dfo.level, dfo.message)
+	}
+
+	if dfo.tag("lazy") != "value" {
+		t.Errorf("expected tag lazy to be \"value\", actual: \"%s\"", dfo.tag("lazy"))
+	}
+}
+
+func TestLog_DebugFuncShouldReturnInvalidContext(t *testing.T) {
+	logger := log.NewLogger(&log.Config{Level: log.LEVEL_DEBUG})
+	err := logger.DebugFunc(func() (string, interface{}) { return "message", "" })
+
+	if _, ok := err.(*log.InvalidContext); !ok {
+		t.Error("expected InvalidContext error")
+	}
+}
+
+func TestLog_InfoShouldPassTheTimeOfTheClockInUTC(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	recordTime := time.Date(2017, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	logger := log.NewLogger(&log.Config{
+		Formatter: dfo.createDummyFormatter(),
+		Output:    dfo.createDummyOutput(),
+		Clock:     func() time.Time { return recordTime },
+	})
+
+	logger.Info("message", nil)
+
+	if !dfo.time.Equal(recordTime) || dfo.time.Location() != time.UTC {
+		t.Errorf("expected %s in UTC, actual: %s", recordTime, dfo.time)
+	}
+}
+
+func TestLog_InfoShouldPassTheTimeInTheConfiguredLocation(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	location := time.FixedZone("CET", 3600)
+	logger := log.NewLogger(&log.Config{
+		Formatter: dfo.createDummyFormatter(),
+		Output:    dfo.createDummyOutput(),
+		Clock:     func() time.Time { return time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC) },
+		Location:  location,
+	})
+
+	logger.Info("message", nil)
+
+	if dfo.time.Location() != location || dfo.time.Hour() != 4 {
+		t.Errorf("expected the time in CET, actual: %s", dfo.time)
+	}
+}
+
+func TestLog_InfoShouldReadTheClockOnceForAllSinks(t *testing.T) {
+	calls := 0
+	encoded, formatted := new(bytes.Buffer), new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{
+		DateFormat: time.RFC3339Nano,
+		Clock: func() time.Time {
+			calls++
+			return time.Date(2017, 1, 2, 3, 4, 5, calls, time.UTC)
+		},
+		Sinks: []log.Sink{
+			{Encoder: log.TextEncoder{}, Writer: encoded},
+			{Formatter: log.FormatterFunc(log.TextFormatter), Writer: formatted},
+		},
+	})
+
+	logger.Info("message", nil)
+
+	if calls != 1 {
+		t.Errorf("expected one call of the clock, actual: %d", calls)
+	}
+	expected := "2017-01-02T03:04:05.000000001Z\tINFO\t\tmessage\tprogram:\n"
+	if encoded.String() != expected || formatted.String() != expected {
+		t.Errorf("expected %q from both sinks, actual: %q and %q", expected, encoded.String(), formatted.String())
+	}
+}
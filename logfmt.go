@@ -0,0 +1,171 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type InvalidLogfmt string
+
+func (err InvalidLogfmt) Error() string {
+	return "invalid logfmt: " + string(err)
+}
+
+// LogfmtFormatter renders a record as space separated key=value pairs. The
+// time, level, msg, function and program keys come first, followed by the
+// other tags in sorted order. Object tags are flattened into dotted keys.
+// Values that are empty or contain spaces, quotes, = or control characters
+// are quoted and escaped like JSON strings. A tag key that consists of
+// underscores followed by time, level or msg gets another underscore
+// prepended to stay unique.
+func LogfmtFormatter(t time.Time, level string, message string, fields Fields, dateFormat string) string {
+	function, _ := fields.Get("function")
+	program, _ := fields.Get("program")
+	output := make([]byte, 0, 256)
+	output = append(output, "time="...)
+	output = appendLogfmtString(output, t.Format(dateFormat))
+	output = append(output, " level="...)
+	output = appendLogfmtString(output, level)
+	output = append(output, " msg="...)
+	output = appendLogfmtString(output, message)
+	output = append(output, " function="...)
+	output = appendLogfmtString(output, function.String())
+	output = append(output, " program="...)
+	output = appendLogfmtString(output, program.String())
+	for _, field := range fields.sorted() {
+		if field.Key == "function" || field.Key == "program" || len(field.Key) == 0 {
+			continue
+		}
+		output = appendLogfmtField(output, "", field)
+	}
+	return string(output)
+}
+
+// ParseLogfmt splits a logfmt record into string fields in the order of the
+// record. A key without a value gets the empty string.
+func ParseLogfmt(record string) (Fields, error) {
+	record = strings.TrimSuffix(record, "\n")
+	fields := Fields{}
+	for i := 0; i < len(record); {
+		if record[i] == ' ' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(record) && record[i] != '=' && record[i] != ' ' {
+			if record[i] == '"' {
+				return nil, InvalidLogfmt("unexpected quote in key at offset " + strconv.Itoa(i))
+			}
+			i++
+		}
+		key := record[start:i]
+		if len(key) == 0 {
+			return nil, InvalidLogfmt("missing key at offset " + strconv.Itoa(i))
+		}
+		if i == len(record) || record[i] == ' ' {
+			fields = append(fields, String(key, ""))
+			continue
+		}
+		i++
+
+		start = i
+		var value string
+		if i < len(record) && record[i] == '"' {
+			for i++; i < len(record) && record[i] != '"'; i++ {
+				if record[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(record) {
+				return nil, InvalidLogfmt("unterminated quoted value at offset " + strconv.Itoa(start))
+			}
+			i++
+			unquoted, err := strconv.Unquote(record[start:i])
+			if err != nil {
+				return nil, InvalidLogfmt("invalid quoted value at offset " + strconv.Itoa(start))
+			}
+			if i < len(record) && record[i] != ' ' {
+				return nil, InvalidLogfmt("missing space after quoted value at offset " + strconv.Itoa(i))
+			}
+			value = unquoted
+		} else {
+			for i < len(record) && record[i] != ' ' {
+				if record[i] == '"' || record[i] == '=' {
+					return nil, InvalidLogfmt("unexpected " + string(record[i]) + " in value at offset " + strconv.Itoa(i))
+				}
+				i++
+			}
+			value = record[start:i]
+		}
+		fields = append(fields, String(key, value))
+	}
+	return fields, nil
+}
+
+func appendLogfmtField(dst []byte, prefix string, field Field) []byte {
+	if field.Value.Kind() == KindObject && len(field.Value.Object()) > 0 {
+		for _, nested := range field.Value.Object() {
+			dst = appendLogfmtField(dst, prefix+field.Key+".", nested)
+		}
+		return dst
+	}
+	dst = append(dst, ' ')
+	if len(prefix) == 0 && isReservedLogfmtKey(field.Key) {
+		dst = append(dst, '_')
+	}
+	dst = appendLogfmtKey(dst, prefix)
+	dst = appendLogfmtKey(dst, field.Key)
+	dst = append(dst, '=')
+	if field.Value.Kind() == KindString {
+		return appendLogfmtString(dst, field.Value.str)
+	}
+	start := len(dst)
+	dst = appendTextValue(dst, field.Value)
+	if needsLogfmtQuote(string(dst[start:])) {
+		text := string(dst[start:])
+		dst = appendJSONString(dst[:start], text)
+	}
+	return dst
+}
+
+// appendLogfmtKey replaces the characters that would end a key with
+// underscores.
+func appendLogfmtKey(dst []byte, key string) []byte {
+	for i := 0; i < len(key); i++ {
+		if b := key[i]; b <= ' ' || b == '=' || b == '"' || b == 0x7f {
+			dst = append(dst, '_')
+		} else {
+			dst = append(dst, b)
+		}
+	}
+	return dst
+}
+
+func appendLogfmtString(dst []byte, s string) []byte {
+	if needsLogfmtQuote(s) {
+		return appendJSONString(dst, s)
+	}
+	return append(dst, s...)
+}
+
+func needsLogfmtQuote(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		if b := s[i]; b <= ' ' || b == '=' || b == '"' || b == 0x7f {
+			return true
+		}
+	}
+	return !utf8.ValidString(s)
+}
+
+func isReservedLogfmtKey(key string) bool {
+	switch strings.TrimLeft(key, "_") {
+	case "time", "level", "msg":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package log
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogfmtFormatterShouldPutTheStandardKeysFirst(t *testing.T) {
+	resultString := LogfmtFormatter(testTime, "INFO", "message", Fields{
+		String("zebra", "z"),
+		String("program", "program name"),
+		String("alpha", "a"),
+		String("function", "handle"),
+	}, "2006")
+
+	result, err := ParseLogfmt(resultString)
+	if err != nil {
+		t.Fatalf("ParseLogfmt failed with error: %s, output: %q", err, resultString)
+	}
+	expected := Fields{
+		String("time", testTime.Format("2006")),
+		String("level", "INFO"),
+		String("msg", "message"),
+		String("function", "handle"),
+		String("program", "program name"),
+		String("alpha", "a"),
+		String("zebra", "z"),
+	}
+	if !result.Equal(expected) {
+		t.Errorf("expected %v, actual: %v", expected, result)
+	}
+}
+
+func TestLogfmtFormatterShouldQuoteValuesThatNeedIt(t *testing.T) {
+	resultString := LogfmtFormatter(testTime, "INFO", "two words", Fields{
+		String("plain", "value"),
+		String("empty", ""),
+		String("equals", "a=b"),
+		String("quote", `say "hi"`),
+		String("newline", "a\nb"),
+		String("path", `C:\dir`),
+	}, "2006")
+
+	for _, expected := range []string{
+		` msg="two words" `,
+		` plain=value`,
+		` empty=""`,
+		` equals="a=b"`,
+		` quote="say \"hi\""`,
+		` newline="a\nb"`,
+		` path=C:\dir`,
+	} {
+		if !strings.Contains(resultString, expected) {
+			t.Errorf("expected %q in %q", expected, resultString)
+		}
+	}
+}
+
+func TestLogfmtFormatterShouldRenderTypedAndNestedValues(t *testing.T) {
+	resultString := LogfmtFormatter(testTime, "INFO", "message", Fields{
+		Int("count", 3),
+		Bool("ok", true),
+		Duration("elapsed", 1500*time.Millisecond),
+		Err("error", errors.New("connection refused")),
+		Object("user", Int("id", 42), String("name", "Jane Doe")),
+		Array("ids", Int64Value(1), Int64Value(2)),
+		String("level", "shadowed"),
+		String("bad key", "value"),
+	}, "2006")
+
+	result, err := ParseLogfmt(resultString)
+	if err != nil {
+		t.Fatalf("ParseLogfmt failed with error: %s, output: %q", err, resultString)
+	}
+	expected := map[string]string{
+		"level":     "INFO",
+		"count":     "3",
+		"ok":        "true",
+		"elapsed":   "1.5s",
+		"error":     "connection refused",
+		"user.id":   "42",
+		"user.name": "Jane Doe",
+		"ids":       "[1 2]",
+		"_level":    "shadowed",
+		"bad_key":   "value",
+	}
+	for key, value := range expected {
+		if actual, ok := result.Get(key); !ok || actual.String() != value {
+			t.Errorf("expected %q to be %q, actual: %q", key, value, actual.String())
+		}
+	}
+}
+
+func TestParseLogfmtShouldAcceptKeysWithoutValue(t *testing.T) {
+	result, err := ParseLogfmt("a=1  flag b=\"x y\"\n")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Fields{String("a", "1"), String("flag", ""), String("b", "x y")}
+	if !result.Equal(expected) {
+		t.Errorf("expected %v, actual: %v", expected, result)
+	}
+}
+
+func TestParseLogfmtShouldRejectInvalidRecords(t *testing.T) {
+	for _, record := range []string{
+		`=value`,
+		`key="unterminated`,
+		`key="quoted"trailing`,
+		`key=a"b`,
+		`key=a=b`,
+		`"key"=value`,
+		`key="\q"`,
+	} {
+		if _, err := ParseLogfmt(record); err == nil {
+			t.Errorf("expected an error for %q", record)
+		} else if _, ok := err.(InvalidLogfmt); !ok {
+			t.Errorf("expected InvalidLogfmt for %q, actual: %#v", record, err)
+		}
+	}
+}
+
+func FuzzLogfmtFormatter(f *testing.F) {
+	f.Add("INFO", "message", "key", "value")
+	f.Add("DEBUG", "\"quoted\"\n", "a=b", "\x00\x1f\x7f")
+	f.Add("L", "invalid \xff\xfe utf8", "msg", "\u2028 = \\")
+	f.Fuzz(func(t *testing.T, level string, message string, key string, value string) {
+		if key == "" || key == "function" || key == "program" {
+			t.Skip()
+		}
+		resultString := LogfmtFormatter(testTime, level, message, Fields{String(key, value)}, "2006")
+
+		if strings.ContainsAny(resultString, "\n\r") {
+			t.Fatalf("expected output on a single line, actual: %q", resultString)
+		}
+		result, err := ParseLogfmt(resultString)
+		if err != nil {
+			t.Fatalf("ParseLogfmt failed with error: %s, output: %q", err, resultString)
+		}
+
+		expectedKey := string(appendLogfmtKey(nil, key))
+		if isReservedLogfmtKey(key) {
+			expectedKey = "_" + expectedKey
+		}
+		expected := map[string]string{
+			"level":     roundTrip(t, level),
+			"msg":       roundTrip(t, message),
+			expectedKey: roundTrip(t, value),
+		}
+		for name, expectedValue := range expected {
+			if actual, _ := result.Get(name); actual.String() != expectedValue {
+				t.Errorf("expected %q to be %q, actual: %q", name, expectedValue, actual.String())
+			}
+		}
+		if len(result) != 6 {
+			t.Errorf("expected exactly 6 keys, actual: %q", resultString)
+		}
+	})
+}
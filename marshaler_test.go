@@ -0,0 +1,78 @@
+package log_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/flowpl/log"
+)
+
+type order struct {
+	id    int64
+	total float64
+	items []string
+}
+
+func (o order) MarshalLog() log.Fields {
+	items := make([]log.Value, len(o.items))
+	for i, item := range o.items {
+		items[i] = log.StringValue(item)
+	}
+	return log.Fields{
+		log.Int64("order_id", o.id),
+		log.Object("total", log.Float64("amount", o.total), log.String("currency", "EUR")),
+		log.Array("items", items...),
+	}
+}
+
+func TestLog_InfoShouldUseTheFieldsOfLogMarshalersInEveryFormat(t *testing.T) {
+	outputs := map[string]*bytes.Buffer{}
+	formatters := map[string]log.Formatter{
+		"json":    log.JsonEncoder{},
+		"text":    log.TextEncoder{},
+		"logfmt":  log.FormatterFunc(log.LogfmtFormatter),
+		"console": log.ConsoleFormatter{},
+	}
+	sinks := []log.Sink{}
+	for name, formatter := range formatters {
+		outputs[name] = new(bytes.Buffer)
+		sinks = append(sinks, log.Sink{Formatter: formatter, Writer: outputs[name]})
+	}
+	logger := log.NewLogger(&log.Config{Sinks: sinks, DateFormat: log.TIME_FORMAT})
+
+	logger.Info("order placed", order{7, 19.5, []string{"book"}})
+
+	expected := map[string][]string{
+		"json":    {`"order_id":7`, `"total":{"amount":19.5,"currency":"EUR"}`, `"items":["book"]`},
+		"text":    {"order_id:7", "total.amount:19.5", "items:[book]"},
+		"logfmt":  {"order_id=7", "total.amount=19.5", "total.currency=EUR"},
+		"console": {"order_id=7", "total.amount=19.5"},
+	}
+	for name, parts := range expected {
+		for _, part := range parts {
+			if !strings.Contains(outputs[name].String(), part) {
+				t.Errorf("%s: expected %q in %q", name, part, outputs[name].String())
+			}
+		}
+	}
+}
+
+type checkout struct {
+	Order order  `log:"order"`
+	User  string `log:"user"`
+}
+
+func TestLog_InfoShouldRenderNestedLogMarshalersAsObjects(t *testing.T) {
+	output := new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{Encoder: log.JsonEncoder{}, Writer: output})
+	child, _ := logger.ChildLogger("child", order{id: 1})
+
+	child.Info("message", log.With(checkout{order{id: 2}, "alice"}, "last", order{id: 3}))
+
+	for _, part := range []string{`"order_id":1`, `"order":{"order_id":2,`, `"last":{"order_id":3,`, `"user":"alice"`} {
+		if !strings.Contains(output.String(), part) {
+			t.Errorf("expected %q in %q", part, output.String())
+		}
+	}
+}
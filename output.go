@@ -2,7 +2,9 @@ package log
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"sync"
 )
 
 func StdOutOutput(messageString string) {
@@ -12,3 +14,58 @@ func StdOutOutput(messageString string) {
 func StdErrOutput(messageString string) {
 	fmt.Fprintln(os.Stderr, messageString)
 }
+
+// OutputFunc adapts a function output like StdOutOutput to an io.Writer. The
+// function receives every record without the trailing newline.
+type OutputFunc func(formattedMessage string)
+
+func (of OutputFunc) Write(record []byte) (int, error) {
+	message := record
+	if len(message) > 0 && message[len(message)-1] == '\n' {
+		message = message[:len(message)-1]
+	}
+	of(string(message))
+	return len(record), nil
+}
+
+type OutputFailed struct {
+	Err error
+}
+
+func (err *OutputFailed) Error() string {
+	return "writing log output failed: " + err.Err.Error()
+}
+
+func (err *OutputFailed) Unwrap() error {
+	return err.Err
+}
+
+// SyncWriter serializes writes to a writer that is shared by loggers in many
+// goroutines. Log writes every record with a single call to Write, SyncWriter
+// completes that call before the next one starts, so records never interleave.
+type SyncWriter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func NewSyncWriter(writer io.Writer) *SyncWriter {
+	return &SyncWriter{writer: writer}
+}
+
+// Write writes all of record, retrying short writes while holding the lock.
+func (sw *SyncWriter) Write(record []byte) (int, error) {
+	sw.mutex.Lock()
+	defer sw.mutex.Unlock()
+	total := 0
+	for total < len(record) {
+		written, err := sw.writer.Write(record[total:])
+		total += written
+		if err != nil {
+			return total, err
+		}
+		if written == 0 {
+			return total, io.ErrShortWrite
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,168 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/flowpl/log"
+)
+
+type failingWriter struct {
+	err     error
+	written int
+}
+
+func (fw *failingWriter) Write(record []byte) (int, error) {
+	if fw.err != nil {
+		return 0, fw.err
+	}
+	return fw.written, nil
+}
+
+func TestLog_InfoShouldReturnOutputFailedIfTheWriterFails(t *testing.T) {
+	writeErr := errors.New("disk full")
+	logger := log.NewLogger(&log.Config{Encoder: log.TextEncoder{}, Writer: &failingWriter{err: writeErr}})
+	err := logger.Info("message", nil)
+
+	outputErr, ok := err.(*log.OutputFailed)
+	if !ok {
+		t.Fatalf("expected OutputFailed error, actual: %v", err)
+	}
+
+	if !errors.Is(outputErr, writeErr) {
+		t.Errorf("expected OutputFailed to wrap the write error, actual: %v", outputErr.Err)
+	}
+}
+
+func TestLog_InfoShouldReturnOutputFailedOnShortWrites(t *testing.T) {
+	logger := log.NewLogger(&log.Config{Formatter: log.FormatterFunc(log.TextFormatter), Writer: &failingWriter{written: 1}})
+	err := logger.Info("message", nil)
+
+	if outputErr, ok := err.(*log.OutputFailed); !ok || outputErr.Err != io.ErrShortWrite {
+		t.Errorf("expected OutputFailed with io.ErrShortWrite, actual: %v", err)
+	}
+}
+
+func TestLog_DebugShouldPassOutputErrorsToTheErrorHandler(t *testing.T) {
+	var handledErr error
+	config := &log.Config{
+		Level:        log.LEVEL_DEBUG,
+		Encoder:      log.JsonEncoder{},
+		Writer:       &failingWriter{err: errors.New("closed pipe")},
+		ErrorHandler: func(err error) { handledErr = err },
+	}
+	logger, _ := log.NewLogger(config).ChildLogger("child", nil)
+	err := logger.Debug("message", nil)
+
+	if err != nil {
+		t.Errorf("expected no error if an ErrorHandler is set, actual: %v", err)
+	}
+
+	if _, ok := handledErr.(*log.OutputFailed); !ok {
+		t.Errorf("expected ErrorHandler to receive OutputFailed, actual: %v", handledErr)
+	}
+}
+
+func TestOutputFuncShouldPassTheRecordWithoutNewline(t *testing.T) {
+	var message string
+	output := log.OutputFunc(func(formattedMessage string) { message = formattedMessage })
+	written, err := output.Write([]byte("record\n"))
+
+	if message != "record" {
+		t.Errorf("expected message \"record\", actual: %q", message)
+	}
+
+	if written != 7 || err != nil {
+		t.Errorf("expected 7 bytes written without error, actual: %d, %v", written, err)
+	}
+}
+
+// chunkedWriter splits every write into small chunks and yields in between,
+// like a writer that is not safe for concurrent use.
+type chunkedWriter struct {
+	output []byte
+}
+
+func (cw *chunkedWriter) Write(record []byte) (int, error) {
+	for start := 0; start < len(record); start += 16 {
+		end := start + 16
+		if end > len(record) {
+			end = len(record)
+		}
+		cw.output = append(cw.output, record[start:end]...)
+		runtime.Gosched()
+	}
+	return len(record), nil
+}
+
+func TestSyncWriterShouldKeepRecordsFromConcurrentLoggersIntact(t *testing.T) {
+	const goroutines, records = 16, 200
+	writer := new(chunkedWriter)
+	config := &log.Config{
+		Level:        log.LEVEL_INFO,
+		Encoder:      log.JsonEncoder{},
+		Writer:       log.NewSyncWriter(writer),
+		FunctionName: "main",
+		DateFormat:   log.TIME_FORMAT,
+	}
+	rootLogger := log.NewLogger(config)
+	longValue := strings.Repeat("x", 4096)
+
+	waitGroup := new(sync.WaitGroup)
+	for i := 0; i < goroutines; i++ {
+		waitGroup.Add(1)
+		go func(worker int) {
+			defer waitGroup.Done()
+			logger, _ := rootLogger.ChildLogger("worker", log.Fields{log.Int("worker", worker)})
+			for j := 0; j < records; j++ {
+				logger.Info("message", log.Fields{log.Int("record", j), log.String("long", longValue)})
+			}
+		}(i)
+	}
+	waitGroup.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(string(writer.output), "\n"), "\n")
+	if len(lines) != goroutines*records {
+		t.Fatalf("expected %d lines, actual: %d", goroutines*records, len(lines))
+	}
+	seen := map[string]bool{}
+	for _, line := range lines {
+		record := struct {
+			Worker int
+			Record int
+			Long   string
+		}{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil || record.Long != longValue {
+			t.Fatalf("expected an intact record, actual: %.100q", line)
+		}
+		seen[fmt.Sprintf("%d/%d", record.Worker, record.Record)] = true
+	}
+	if len(seen) != goroutines*records {
+		t.Errorf("expected %d distinct records, actual: %d", goroutines*records, len(seen))
+	}
+}
+
+func TestSyncWriterShouldCompleteShortWrites(t *testing.T) {
+	writer := new(bytes.Buffer)
+	syncWriter := log.NewSyncWriter(&oneByteWriter{writer})
+	written, err := syncWriter.Write([]byte("record\n"))
+
+	if written != 7 || err != nil || writer.String() != "record\n" {
+		t.Errorf("expected the complete record to be written, actual: %d, %v, %q", written, err, writer.String())
+	}
+}
+
+type oneByteWriter struct {
+	writer io.Writer
+}
+
+func (obw *oneByteWriter) Write(record []byte) (int, error) {
+	return obw.writer.Write(record[:1])
+}
@@ -0,0 +1,83 @@
+package log
+
+import (
+	"time"
+)
+
+// Record is a log record as it is passed to a Formatter.
+type Record struct {
+	Time     time.Time
+	Level    string
+	Message  string
+	Program  string
+	Function string
+	// Fields are the tags of the logger merged with the context of the call,
+	// including the program and function tags, in no particular order.
+	Fields Fields
+	// Caller is the source location of the call, if it was captured.
+	Caller Caller
+	// Stack is the formatted stack of the call, if it was captured.
+	Stack string
+	// Error is the value of the error tag, if it holds an error.
+	Error error
+	// Sequence numbers the records of a root logger and its child loggers,
+	// starting at 1.
+	Sequence uint64
+	// DateFormat is the layout of Config.DateFormat for Time.
+	DateFormat string
+}
+
+// Caller is a location in the source code.
+type Caller struct {
+	File     string
+	Line     int
+	Function string
+}
+
+// Formatter appends a record to dst and returns the extended buffer. It is
+// called for every record and must not keep the record.
+type Formatter interface {
+	Format(dst []byte, record *Record) []byte
+}
+
+// FormatterFunc adapts a function-style formatter like TextFormatter to the
+// Formatter interface.
+type FormatterFunc func(t time.Time, level string, message string, fields Fields, dateFormat string) string
+
+func (ff FormatterFunc) Format(dst []byte, record *Record) []byte {
+	return append(dst, ff(record.Time, record.Level, record.Message, record.Fields, record.DateFormat)...)
+}
+
+// NewFormatter adapts a function-style formatter like FormatterFunc. Function
+// values cannot be compared, so sinks with the same FormatterFunc format a
+// record once per sink; sinks that share the returned formatter format it
+// once.
+func NewFormatter(format FormatterFunc) Formatter {
+	return &format
+}
+
+// Logfmt renders a record like LogfmtFormatter. Sinks with Logfmt share the
+// rendering of a record.
+type Logfmt struct{}
+
+func (Logfmt) Format(dst []byte, record *Record) []byte {
+	return append(dst, LogfmtFormatter(record.Time, record.Level, record.Message, record.Fields, record.DateFormat)...)
+}
+
+// Format renders a record like TextFormatter.
+func (encoder TextEncoder) Format(dst []byte, record *Record) []byte {
+	return appendWithEncoder(dst, encoder, record)
+}
+
+// Format renders a record like JsonFormatter.
+func (encoder JsonEncoder) Format(dst []byte, record *Record) []byte {
+	return appendWithEncoder(dst, encoder, record)
+}
+
+func appendWithEncoder(dst []byte, encoder Encoder, record *Record) []byte {
+	dst = encoder.AppendHeader(dst, record.Time, record.DateFormat, record.Level, record.Message, record.Function)
+	for _, field := range record.Fields.sorted() {
+		dst = encoder.AppendField(dst, field)
+	}
+	return encoder.AppendFooter(dst)
+}
@@ -0,0 +1,140 @@
+package log_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flowpl/log"
+)
+
+type recordingFormatter struct {
+	records []log.Record
+}
+
+func (rf *recordingFormatter) Format(dst []byte, record *log.Record) []byte {
+	rf.records = append(rf.records, *record)
+	return append(dst, record.Message...)
+}
+
+func TestLog_InfoShouldPassTheRecordToTheFormatter(t *testing.T) {
+	formatter := new(recordingFormatter)
+	recordTime := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+	failure := errors.New("failed")
+	logger := log.NewLogger(&log.Config{
+		Formatter:    formatter,
+		Writer:       new(bytes.Buffer),
+		ProgramName:  "program",
+		FunctionName: "main",
+		DateFormat:   "2006",
+		Clock:        func() time.Time { return recordTime },
+	})
+
+	logger.Info("message", failure)
+
+	if len(formatter.records) != 1 {
+		t.Fatalf("expected one record, actual: %d", len(formatter.records))
+	}
+	record := formatter.records[0]
+	if !record.Time.Equal(recordTime) || record.Level != log.LEVEL_INFO || record.Message != "message" ||
+		record.Program != "program" || record.Function != "main" || record.DateFormat != "2006" {
+		t.Errorf("unexpected record %#v", record)
+	}
+	if record.Error != failure {
+		t.Errorf("expected the error of the context, actual: %v", record.Error)
+	}
+	if value, _ := record.Fields.Get("program"); value.String() != "program" {
+		t.Errorf("expected the program tag in the fields, actual: %v", record.Fields)
+	}
+}
+
+func TestLog_InfoShouldNumberTheRecordsOfAllChildLoggers(t *testing.T) {
+	formatter := new(recordingFormatter)
+	logger := log.NewLogger(&log.Config{Level: log.LEVEL_DEBUG, Formatter: formatter, Writer: new(bytes.Buffer)})
+	child, _ := logger.ChildLogger("child", nil)
+
+	logger.Info("first", nil)
+	child.Debug("second", nil)
+	logger.Info("third", nil)
+
+	for i, record := range formatter.records {
+		if record.Sequence != uint64(i+1) {
+			t.Errorf("expected sequence %d for %q, actual: %d", i+1, record.Message, record.Sequence)
+		}
+	}
+	if len(formatter.records) != 3 || formatter.records[1].Function != "child" {
+		t.Errorf("unexpected records %#v", formatter.records)
+	}
+}
+
+func TestLog_InfoShouldFormatOnceForSinksWithTheSameFormatter(t *testing.T) {
+	formatter := new(recordingFormatter)
+	first, second := new(bytes.Buffer), new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{
+		Sinks: []log.Sink{
+			{Formatter: formatter, Writer: first},
+			{Formatter: formatter, Writer: second},
+		},
+	})
+
+	logger.Info("message", nil)
+
+	if len(formatter.records) != 1 {
+		t.Errorf("expected one formatting, actual: %d", len(formatter.records))
+	}
+	if first.String() != "message\n" || second.String() != "message\n" {
+		t.Errorf("expected the record in both sinks, actual: %q and %q", first.String(), second.String())
+	}
+}
+
+func TestLog_InfoShouldRenderTheSameWithEncodersAsFormatters(t *testing.T) {
+	formatters := map[log.Encoder]log.FormatterFunc{log.TextEncoder{}: log.TextFormatter, log.JsonEncoder{}: log.JsonFormatter}
+	contexts := []log.Fields{{log.Int("count", 1)}, {log.Int("count", 1), log.String("function", "other")}}
+	for encoder, formatterFunc := range formatters {
+		for _, context := range contexts {
+			encoded, formatted, formattedFunc := new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer)
+			clock := func() time.Time { return time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC) }
+			tags := log.Fields{log.String("tag", "value")}
+			log.NewLogger(&log.Config{Encoder: encoder, Writer: encoded, FunctionName: "main", DateFormat: "2006", Tags: tags, Clock: clock}).
+				Info("message", context)
+			log.NewLogger(&log.Config{Formatter: encoder.(log.Formatter), Writer: formatted, FunctionName: "main", DateFormat: "2006", Tags: tags, Clock: clock}).
+				Info("message", context)
+			log.NewLogger(&log.Config{Formatter: formatterFunc, Writer: formattedFunc, FunctionName: "main", DateFormat: "2006", Tags: tags, Clock: clock}).
+				Info("message", context)
+
+			if encoded.String() != formatted.String() || encoded.String() != formattedFunc.String() {
+				t.Errorf("expected encoder output %q to equal formatter outputs %q and %q", encoded.String(), formatted.String(), formattedFunc.String())
+			}
+			if _, overridden := context.Get("function"); overridden && strings.Contains(encoded.String(), "main") {
+				t.Errorf("expected the function of the call in %q", encoded.String())
+			}
+		}
+	}
+}
+
+func TestLog_InfoShouldFormatOnceForSinksSharingAFormatterFunc(t *testing.T) {
+	calls := 0
+	formatter := log.NewFormatter(func(t time.Time, level string, message string, fields log.Fields, dateFormat string) string {
+		calls++
+		return log.LogfmtFormatter(t, level, message, fields, dateFormat)
+	})
+	first, second, third := new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{
+		Sinks: []log.Sink{
+			{Formatter: formatter, Writer: first},
+			{Formatter: formatter, Writer: second},
+			{Formatter: log.Logfmt{}, Writer: third},
+		},
+	})
+
+	logger.Info("message", nil)
+
+	if calls != 1 {
+		t.Errorf("expected one formatting, actual: %d", calls)
+	}
+	if first.String() != second.String() || first.String() != third.String() || !strings.Contains(first.String(), "msg=message") {
+		t.Errorf("expected the same record in all sinks, actual: %q, %q and %q", first.String(), second.String(), third.String())
+	}
+}
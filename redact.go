@@ -0,0 +1,242 @@
+package log
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// RedactedMask replaces redacted values unless Redactor.Mask is set.
+const RedactedMask = "[REDACTED]"
+
+// Patterns for Redactor.Values. CreditCardNumbers matches 13 to 19 digits,
+// optionally grouped by spaces or dashes, and masks only numbers that pass
+// the Luhn check, so that order ids or timestamps are kept.
+var (
+	CreditCardNumbers = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	BearerTokens      = regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9._~+/=-]+`)
+	EmailAddresses    = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+)
+
+// matchValidators holds the checks of patterns whose matches are only masked
+// if they pass.
+var matchValidators = map[*regexp.Regexp]func(string) bool{
+	CreditCardNumbers: luhnValid,
+}
+
+// redaction marks values that are redacted or have to be.
+type redaction uint8
+
+const (
+	redactByTag redaction = iota + 1 // set by the redact option of struct tags
+	redacted
+)
+
+var redactedValue = Value{kind: KindString, str: RedactedMask, any: redactByTag}
+
+// Redactor masks sensitive tags before they reach any formatter. It is set on
+// Config and shared by a logger and all of its child loggers:
+//
+//	logConfig.Redactor = &log.Redactor{
+//		Keys:   []string{"password", "*_token"},
+//		Values: []*regexp.Regexp{log.CreditCardNumbers, log.BearerTokens},
+//	}
+//
+// Struct fields with the redact option, `log:"token,redact"`, are masked as
+// well, with or without a Redactor.
+type Redactor struct {
+	keys   uint64 // first for the alignment of atomic operations
+	tags   uint64
+	values uint64
+
+	// Keys are patterns of path.Match. They are matched case insensitively
+	// against the keys of tags and the last segment of dotted keys, tags that
+	// match are masked as a whole.
+	Keys []string
+	// Values replace their matches in string values and in the messages of
+	// errors with the mask. The fields of errors are redacted like tags.
+	Values []*regexp.Regexp
+	// Mask replaces redacted values, RedactedMask if it is empty.
+	Mask string
+}
+
+// RedactionCounts counts the tags masked by key, by struct tag and the string
+// values and error messages changed by a value pattern.
+type RedactionCounts struct {
+	Keys   uint64
+	Tags   uint64
+	Values uint64
+}
+
+func (redactor *Redactor) Counts() RedactionCounts {
+	return RedactionCounts{
+		Keys:   atomic.LoadUint64(&redactor.keys),
+		Tags:   atomic.LoadUint64(&redactor.tags),
+		Values: atomic.LoadUint64(&redactor.values),
+	}
+}
+
+// redact masks fields in place. Nested objects and arrays are copied when
+// they change.
+func (redactor *Redactor) redact(fields Fields) {
+	for i := range fields {
+		fields[i].Value, _ = redactor.redactValue(fields[i].Key, fields[i].Value)
+	}
+}
+
+func (redactor *Redactor) redactValue(key string, value Value) (Value, bool) {
+	if mark, ok := value.any.(redaction); ok {
+		if mark == redacted {
+			return value, false
+		}
+		atomic.AddUint64(&redactor.tags, 1)
+		return redactor.masked(), true
+	}
+	if redactor.matchesKey(key) {
+		atomic.AddUint64(&redactor.keys, 1)
+		return redactor.masked(), true
+	}
+	switch value.kind {
+	case KindString:
+		if str := redactor.replaceAll(value.str); str != value.str {
+			atomic.AddUint64(&redactor.values, 1)
+			return Value{kind: KindString, str: str, any: redacted}, true
+		}
+	case KindError:
+		if err := value.Err(); err != nil {
+			if redactedErr, changed := redactor.redactError(err); changed {
+				return ErrorValue(redactedErr), true
+			}
+		}
+	case KindObject:
+		var copied Fields
+		for i, field := range value.Object() {
+			if redactedField, changed := redactor.redactValue(field.Key, field.Value); changed {
+				if copied == nil {
+					copied = append(Fields(nil), value.Object()...)
+				}
+				copied[i].Value = redactedField
+			}
+		}
+		if copied != nil {
+			return ObjectValue(copied...), true
+		}
+	case KindArray:
+		var copied []Value
+		for i, element := range value.Array() {
+			if redactedElement, changed := redactor.redactValue(key, element); changed {
+				if copied == nil {
+					copied = append([]Value(nil), value.Array()...)
+				}
+				copied[i] = redactedElement
+			}
+		}
+		if copied != nil {
+			return ArrayValue(copied...), true
+		}
+	}
+	return value, false
+}
+
+// redactError applies the value patterns to the messages of an error and
+// its chain, and the key and value rules to its fields.
+func (redactor *Redactor) redactError(err error) (error, bool) {
+	original := describeError(err)
+	details := *original
+	changed := false
+	details.message = redactor.replaceAll(details.message)
+	if details.message != original.message {
+		changed = true
+	}
+	details.chain = make([]errorLayer, len(original.chain))
+	for i, layer := range original.chain {
+		details.chain[i] = errorLayer{redactor.replaceAll(layer.message), layer.typeName}
+		if details.chain[i].message != layer.message {
+			changed = true
+		}
+	}
+	if changed {
+		atomic.AddUint64(&redactor.values, 1)
+	}
+	details.fields = append(Fields(nil), original.fields...)
+	for i, field := range details.fields {
+		if redactedField, fieldChanged := redactor.redactValue(field.Key, field.Value); fieldChanged {
+			details.fields[i].Value = redactedField
+			changed = true
+		}
+	}
+	if !changed {
+		return err, false
+	}
+	return &redactedError{&details}, true
+}
+
+func (redactor *Redactor) replaceAll(s string) string {
+	for _, pattern := range redactor.Values {
+		s = redactor.replaceMatches(pattern, s)
+	}
+	return s
+}
+
+func (redactor *Redactor) replaceMatches(pattern *regexp.Regexp, s string) string {
+	if !pattern.MatchString(s) {
+		return s
+	}
+	valid, ok := matchValidators[pattern]
+	if !ok {
+		return pattern.ReplaceAllLiteralString(s, redactor.mask())
+	}
+	return pattern.ReplaceAllStringFunc(s, func(match string) string {
+		if valid(match) {
+			return redactor.mask()
+		}
+		return match
+	})
+}
+
+// luhnValid reports whether the digits of number pass the Luhn check.
+func luhnValid(number string) bool {
+	sum, digits := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		if number[i] < '0' || number[i] > '9' {
+			continue
+		}
+		digit := int(number[i] - '0')
+		if digits%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		digits++
+	}
+	return digits > 0 && sum%10 == 0
+}
+
+func (redactor *Redactor) matchesKey(key string) bool {
+	key = strings.ToLower(key)
+	segment := key[strings.LastIndexByte(key, '.')+1:]
+	for _, pattern := range redactor.Keys {
+		pattern = strings.ToLower(pattern)
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, segment); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (redactor *Redactor) mask() string {
+	if redactor.Mask == "" {
+		return RedactedMask
+	}
+	return redactor.Mask
+}
+
+func (redactor *Redactor) masked() Value {
+	return Value{kind: KindString, str: redactor.mask(), any: redacted}
+}
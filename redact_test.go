@@ -0,0 +1,183 @@
+package log_test
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/flowpl/log"
+)
+
+type credentials struct {
+	User     string `log:"user"`
+	Password string `json:"password"`
+	APIToken string `log:"api_token"`
+	Session  string `log:"session,redact"`
+}
+
+func TestLog_InfoShouldRedactTagsByKey(t *testing.T) {
+	output := new(bytes.Buffer)
+	redactor := &log.Redactor{Keys: []string{"password", "*_token"}}
+	logger := log.NewLogger(&log.Config{Encoder: log.JsonEncoder{}, Writer: output, Redactor: redactor})
+
+	logger.Info("login", log.With(
+		credentials{"alice", "secret", "t0k3n", "s3ss10n"},
+		"Password", "secret",
+		"account", map[string]interface{}{"password": "secret", "id": 7},
+	))
+
+	for _, part := range []string{
+		`"user":"alice"`,
+		`"password":"[REDACTED]"`,
+		`"Password":"[REDACTED]"`,
+		`"api_token":"[REDACTED]"`,
+		`"session":"[REDACTED]"`,
+		`"account":{"id":7,"password":"[REDACTED]"}`,
+	} {
+		if !strings.Contains(output.String(), part) {
+			t.Errorf("expected %q in %q", part, output.String())
+		}
+	}
+	if strings.Contains(output.String(), "secret") || strings.Contains(output.String(), "t0k3n") || strings.Contains(output.String(), "s3ss10n") {
+		t.Errorf("expected no secrets, actual: %q", output.String())
+	}
+	if counts := redactor.Counts(); counts != (log.RedactionCounts{Keys: 4, Tags: 1}) {
+		t.Errorf("unexpected counts %+v", counts)
+	}
+}
+
+type account struct {
+	Owner credentials `log:"owner"`
+}
+
+func TestLog_InfoShouldRedactTheLastSegmentOfNestedKeys(t *testing.T) {
+	dfo := new(DummyFormatOutput)
+	logger := log.NewLogger(&log.Config{
+		Formatter: dfo.createDummyFormatter(),
+		Output:    dfo.createDummyOutput(),
+		Redactor:  &log.Redactor{Keys: []string{"password"}, Mask: "***"},
+	})
+
+	logger.Info("message", account{credentials{User: "alice", Password: "secret"}})
+
+	if dfo.tag("owner.password") != "***" || dfo.tag("owner.user") != "alice" {
+		t.Errorf("unexpected tags %v", dfo.tags)
+	}
+}
+
+func TestLog_InfoShouldRedactStringValuesByPattern(t *testing.T) {
+	output := new(bytes.Buffer)
+	redactor := &log.Redactor{Values: []*regexp.Regexp{log.CreditCardNumbers, log.BearerTokens, log.EmailAddresses}}
+	logger := log.NewLogger(&log.Config{Encoder: log.TextEncoder{}, Writer: output, Redactor: redactor})
+
+	logger.Info("payment", log.With(
+		"card", "paid with 4111 1111 1111 1111 today",
+		"header", "Bearer eyJhbGciOi.J9.abc",
+		"contact", "mail alice@example.com or bob@example.org",
+		"hosts", []string{"ok", "carol@example.net"},
+		"amount", 1234567890123,
+	))
+
+	for _, part := range []string{
+		"card:paid with [REDACTED] today",
+		"header:[REDACTED]",
+		"contact:mail [REDACTED] or [REDACTED]",
+		"hosts:[ok [REDACTED]]",
+		"amount:1234567890123",
+	} {
+		if !strings.Contains(output.String(), part) {
+			t.Errorf("expected %q in %q", part, output.String())
+		}
+	}
+	if counts := redactor.Counts(); counts.Values != 4 {
+		t.Errorf("expected 4 redacted values, actual: %+v", counts)
+	}
+}
+
+func TestLog_ChildLoggerShouldInheritTheRedactor(t *testing.T) {
+	output := new(bytes.Buffer)
+	redactor := &log.Redactor{Keys: []string{"password"}}
+	logger := log.NewLogger(&log.Config{Encoder: log.JsonEncoder{}, Writer: output, Redactor: redactor, Tags: log.Fields{log.String("password", "root")}})
+
+	child, _ := logger.ChildLogger("child", credentials{Password: "secret"})
+	grandchild, _ := child.ChildLogger("grandchild", nil)
+	grandchild.Info("message", nil)
+
+	if strings.Contains(output.String(), "root") || strings.Contains(output.String(), "secret") {
+		t.Errorf("expected no secrets, actual: %q", output.String())
+	}
+	if counts := redactor.Counts(); counts.Keys != 2 {
+		t.Errorf("expected every tag to be counted once, actual: %+v", counts)
+	}
+}
+
+func TestLog_InfoShouldRedactOnlyCardNumbersPassingTheLuhnCheck(t *testing.T) {
+	output := new(bytes.Buffer)
+	redactor := &log.Redactor{Values: []*regexp.Regexp{log.CreditCardNumbers}}
+	logger := log.NewLogger(&log.Config{Encoder: log.TextEncoder{}, Writer: output, Redactor: redactor})
+
+	logger.Info("payment", log.With(
+		"order", "order 1234567890123456",
+		"created", "1476453123456",
+		"card", "5555-5555-5555-4444 and 1234567890123456",
+	))
+
+	for _, part := range []string{"order:order 1234567890123456", "created:1476453123456", "card:[REDACTED] and 1234567890123456"} {
+		if !strings.Contains(output.String(), part) {
+			t.Errorf("expected %q in %q", part, output.String())
+		}
+	}
+	if counts := redactor.Counts(); counts.Values != 1 {
+		t.Errorf("expected 1 redacted value, actual: %+v", counts)
+	}
+}
+
+type loginError struct {
+	user     string
+	password string
+}
+
+func (le loginError) Error() string {
+	return "login failed for " + le.user
+}
+
+func (le loginError) ErrorFields() log.Fields {
+	return log.Fields{log.String("password", le.password), log.String("contact", le.user)}
+}
+
+func TestLog_InfoShouldRedactTheMessagesAndFieldsOfErrors(t *testing.T) {
+	output := new(bytes.Buffer)
+	redactor := &log.Redactor{Keys: []string{"password"}, Values: []*regexp.Regexp{log.EmailAddresses}}
+	formatter := new(recordingFormatter)
+	logger := log.NewLogger(&log.Config{
+		Sinks: []log.Sink{
+			{Encoder: log.JsonEncoder{}, Writer: output},
+			{Formatter: formatter, Writer: new(bytes.Buffer)},
+		},
+		Redactor: redactor,
+	})
+	err := fmt.Errorf("authenticate: %w", loginError{"bob@example.com", "hunter2"})
+
+	logger.Info("login", err)
+
+	if strings.Contains(output.String(), "hunter2") || strings.Contains(output.String(), "bob@example.com") {
+		t.Errorf("expected no secrets, actual: %q", output.String())
+	}
+	for _, part := range []string{
+		`"message":"authenticate: login failed for [REDACTED]"`,
+		`{"message":"login failed for [REDACTED]","type":"log_test.loginError"}`,
+		`"fields":{"password":"[REDACTED]","contact":"[REDACTED]"}`,
+	} {
+		if !strings.Contains(output.String(), part) {
+			t.Errorf("expected %q in %q", part, output.String())
+		}
+	}
+	if recorded := formatter.records[0].Error; recorded == nil || strings.Contains(recorded.Error(), "bob@example.com") {
+		t.Errorf("expected the redacted error in the record, actual: %v", recorded)
+	}
+	if counts := redactor.Counts(); counts != (log.RedactionCounts{Keys: 1, Values: 2}) {
+		t.Errorf("unexpected counts %+v", counts)
+	}
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Sink is one destination of the records of a logger, with its own level,
+// format and writer.
+type Sink struct {
+	// Level is the minimum level written to the sink. An empty Level writes
+	// every record the logger emits.
+	Level     string
+	Formatter Formatter
+	// Encoder, when set, is used instead of Formatter. Sinks with equal
+	// encoders or formatters share the rendering of a record.
+	Encoder Encoder
+	Writer  io.Writer
+}
+
+func (sink Sink) enabled(level string, extended bool) bool {
+	return sink.Level == "" || levelEnabled(sink.Level, level, extended)
+}
+
+// OutputsFailed is returned when writing a record failed for more than one
+// sink.
+type OutputsFailed []error
+
+func (errs OutputsFailed) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (errs OutputsFailed) Unwrap() []error {
+	return errs
+}
+
+// logSink is a sink together with the index of its encoder in Log.encoders
+// or of its formatter in Log.formatters, the other index is -1.
+type logSink struct {
+	Sink
+	encoder   int
+	formatter int
+}
+
+// sinkEncoder is a distinct encoder of the sinks of a logger and the tags of
+// the logger rendered by it.
+type sinkEncoder struct {
+	encoder  Encoder
+	rendered renderedFields
+}
+
+// newSinks returns the sinks of config and their distinct encoders. Without
+// Config.Sinks the Formatter, Encoder and Writer of config form a single sink.
+func newSinks(config *Config) ([]logSink, []sinkEncoder, []Formatter) {
+	sinks := config.Sinks
+	if len(sinks) == 0 {
+		sinks = []Sink{{Formatter: config.Formatter, Encoder: config.Encoder, Writer: config.Writer}}
+	}
+	logSinks := make([]logSink, len(sinks))
+	encoders := []sinkEncoder{}
+	formatters := []Formatter{}
+	for i, sink := range sinks {
+		logSinks[i] = logSink{sink, -1, -1}
+		if sink.Encoder != nil {
+			for j := range encoders {
+				if sameValue(encoders[j].encoder, sink.Encoder) {
+					logSinks[i].encoder = j
+					break
+				}
+			}
+			if logSinks[i].encoder < 0 {
+				logSinks[i].encoder = len(encoders)
+				encoders = append(encoders, sinkEncoder{sink.Encoder, renderFields(sink.Encoder, config.Tags)})
+			}
+			continue
+		}
+		for j := range formatters {
+			if sameValue(formatters[j], sink.Formatter) {
+				logSinks[i].formatter = j
+				break
+			}
+		}
+		if logSinks[i].formatter < 0 {
+			logSinks[i].formatter = len(formatters)
+			formatters = append(formatters, sink.Formatter)
+		}
+	}
+	return logSinks, encoders, formatters
+}
+
+// sameValue compares encoders or formatters without panicking on
+// uncomparable values, which includes comparable structs holding functions in
+// interface fields. Functions are never the same, NewFormatter gives them an
+// identity.
+func sameValue(a interface{}, b interface{}) (same bool) {
+	aType := reflect.TypeOf(a)
+	if aType != reflect.TypeOf(b) || !aType.Comparable() {
+		return false
+	}
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+// joinErrors adds err to the errors of previous sinks.
+func joinErrors(errs error, err error) error {
+	switch {
+	case err == nil:
+		return errs
+	case errs == nil:
+		return err
+	}
+	if outputsFailed, ok := errs.(OutputsFailed); ok {
+		return append(outputsFailed, err)
+	}
+	return OutputsFailed{errs, err}
+}
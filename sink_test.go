@@ -0,0 +1,187 @@
+package log_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flowpl/log"
+)
+
+type countingEncoder struct {
+	log.JsonEncoder
+	headers int
+}
+
+func (ce *countingEncoder) AppendHeader(dst []byte, t time.Time, dateFormat string, level string, message string, function string) []byte {
+	ce.headers++
+	return ce.JsonEncoder.AppendHeader(dst, t, dateFormat, level, message, function)
+}
+
+func TestLog_InfoShouldWriteToAllSinksInTheirFormat(t *testing.T) {
+	text, jsonOutput := new(bytes.Buffer), new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{
+		Level:        log.LEVEL_INFO,
+		ProgramName:  "program",
+		FunctionName: "function",
+		DateFormat:   log.TIME_FORMAT,
+		Tags:         log.Fields{log.String("tag", "value")},
+		Sinks: []log.Sink{
+			{Formatter: log.FormatterFunc(log.TextFormatter), Writer: text},
+			{Encoder: log.JsonEncoder{}, Writer: jsonOutput},
+		},
+	})
+
+	if err := logger.Info("message", log.Int("count", 3)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "\tINFO\tfunction\tmessage\tcount:3,program:program,tag:value\n") {
+		t.Errorf("unexpected text record %q", text.String())
+	}
+	record := map[string]interface{}{}
+	if err := json.Unmarshal(jsonOutput.Bytes(), &record); err != nil {
+		t.Fatalf("invalid json record %q: %s", jsonOutput.String(), err)
+	}
+	if record["message"] != "message" || record["count"] != 3.0 || record["tag"] != "value" {
+		t.Errorf("unexpected json record %q", jsonOutput.String())
+	}
+}
+
+func TestLog_DebugShouldOnlyWriteToSinksWithLevelDebug(t *testing.T) {
+	debug, info := new(bytes.Buffer), new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{
+		Level:      log.LEVEL_DEBUG,
+		DateFormat: log.TIME_FORMAT,
+		Sinks: []log.Sink{
+			{Level: log.LEVEL_DEBUG, Encoder: log.TextEncoder{}, Writer: debug},
+			{Level: log.LEVEL_INFO, Encoder: log.TextEncoder{}, Writer: info},
+		},
+	})
+
+	logger.Debug("debug message", nil)
+	logger.Info("info message", nil)
+
+	if !strings.Contains(debug.String(), "debug message") || !strings.Contains(debug.String(), "info message") {
+		t.Errorf("expected both records in debug sink, got %q", debug.String())
+	}
+	if strings.Contains(info.String(), "debug message") || !strings.Contains(info.String(), "info message") {
+		t.Errorf("expected only the info record in info sink, got %q", info.String())
+	}
+}
+
+func TestLog_EnabledShouldBeFalseIfNoSinkAcceptsTheLevel(t *testing.T) {
+	logger := log.NewLogger(&log.Config{
+		Level: log.LEVEL_DEBUG,
+		Sinks: []log.Sink{
+			{Level: log.LEVEL_INFO, Encoder: log.JsonEncoder{}, Writer: new(bytes.Buffer)},
+			{Level: log.LEVEL_INFO, Formatter: log.FormatterFunc(log.JsonFormatter), Writer: new(bytes.Buffer)},
+		},
+	})
+
+	if logger.Enabled(log.LEVEL_DEBUG) {
+		t.Error("expected debug to be disabled")
+	}
+	if !logger.Enabled(log.LEVEL_INFO) {
+		t.Error("expected info to be enabled")
+	}
+}
+
+func TestLog_InfoShouldEncodeOnceForSinksWithTheSameEncoder(t *testing.T) {
+	encoder := new(countingEncoder)
+	first, second := new(bytes.Buffer), new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{
+		Level:      log.LEVEL_INFO,
+		DateFormat: log.TIME_FORMAT,
+		Sinks: []log.Sink{
+			{Encoder: encoder, Writer: first},
+			{Encoder: encoder, Writer: second},
+		},
+	})
+
+	logger.Info("message", nil)
+
+	if encoder.headers != 1 {
+		t.Errorf("expected one encoding, got %d", encoder.headers)
+	}
+	if first.Len() == 0 || first.String() != second.String() {
+		t.Errorf("expected the same record in both sinks, got %q and %q", first.String(), second.String())
+	}
+}
+
+func TestLog_InfoShouldWriteToTheOtherSinksIfOneFails(t *testing.T) {
+	writeErr := errors.New("disk full")
+	output := new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{
+		Level:      log.LEVEL_INFO,
+		DateFormat: log.TIME_FORMAT,
+		Sinks: []log.Sink{
+			{Encoder: log.JsonEncoder{}, Writer: &failingWriter{err: writeErr}},
+			{Encoder: log.JsonEncoder{}, Writer: output},
+			{Formatter: log.FormatterFunc(log.TextFormatter), Writer: &failingWriter{err: writeErr}},
+		},
+	})
+
+	err := logger.Info("message", nil)
+
+	if !strings.Contains(output.String(), `"message":"message"`) {
+		t.Errorf("expected the record in the working sink, got %q", output.String())
+	}
+	outputsFailed, ok := err.(log.OutputsFailed)
+	if !ok || len(outputsFailed) != 2 {
+		t.Fatalf("expected OutputsFailed with two errors, got %#v", err)
+	}
+	for _, sinkErr := range outputsFailed {
+		if outputFailed, ok := sinkErr.(*log.OutputFailed); !ok || outputFailed.Err != writeErr {
+			t.Errorf("expected OutputFailed wrapping the write error, got %#v", sinkErr)
+		}
+	}
+}
+
+func TestLog_ChildLoggerShouldWriteToTheSinksOfItsParent(t *testing.T) {
+	text, jsonOutput := new(bytes.Buffer), new(bytes.Buffer)
+	logger := log.NewLogger(&log.Config{
+		Level:        log.LEVEL_INFO,
+		FunctionName: "parent",
+		DateFormat:   log.TIME_FORMAT,
+		Sinks: []log.Sink{
+			{Encoder: log.TextEncoder{}, Writer: text},
+			{Encoder: log.JsonEncoder{}, Writer: jsonOutput},
+		},
+	})
+
+	child, _ := logger.ChildLogger("child", log.String("request", "42"))
+	child.Info("message", nil)
+
+	if !strings.Contains(text.String(), "\tchild\tmessage\t") || !strings.Contains(text.String(), "request:42") {
+		t.Errorf("unexpected text record %q", text.String())
+	}
+	if !strings.Contains(jsonOutput.String(), `"function":"child"`) || !strings.Contains(jsonOutput.String(), `"request":"42"`) {
+		t.Errorf("unexpected json record %q", jsonOutput.String())
+	}
+}
+
+type wrappingFormatter struct {
+	log.Formatter
+}
+
+func TestNewLoggerShouldAcceptSinksWithStructsHoldingFunctions(t *testing.T) {
+	first, second := new(bytes.Buffer), new(bytes.Buffer)
+	formatter := wrappingFormatter{log.FormatterFunc(log.TextFormatter)}
+	logger := log.NewLogger(&log.Config{
+		Level:      log.LEVEL_INFO,
+		DateFormat: log.TIME_FORMAT,
+		Sinks: []log.Sink{
+			{Formatter: formatter, Writer: first},
+			{Formatter: formatter, Writer: second},
+		},
+	})
+
+	logger.Info("message", nil)
+
+	if !strings.Contains(first.String(), "\tmessage\t") || first.String() != second.String() {
+		t.Errorf("expected the record in both sinks, got %q and %q", first.String(), second.String())
+	}
+}
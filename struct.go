@@ -0,0 +1,126 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const maxStructDepth = 16
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	stringerType  = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	marshalerType = reflect.TypeOf((*LogMarshaler)(nil)).Elem()
+)
+
+// structTags sets a tag for every exported field of a struct. Keys are taken
+// from the log struct tag, the json tag or the field name:
+//
+//	type Request struct {
+//		Path     string `log:"path"`
+//		Password string `log:"-"`                 // never logged
+//		Token    string `log:"token,redact"`      // logged as [REDACTED]
+//		Retries  int    `log:"retries,omitempty"` // left out when zero
+//		Address                                  // fields of embedded structs are flattened
+//		User     User   `json:"user"`            // nested structs become user.id, user.name
+//	}
+func structTags(tags Fields, value reflect.Value, prefix string, depth int) Fields {
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := parseStructTag(field)
+		if tag.skip {
+			continue
+		}
+		fieldValue := value.Field(i)
+		nested, isStruct := nestedStruct(fieldValue)
+		if field.Anonymous && tag.name == "" && isStruct && !tag.redact {
+			if nested.IsValid() {
+				tags = structTags(tags, nested, prefix, depth+1)
+			}
+			continue
+		}
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+		if tag.omitEmpty && fieldValue.IsZero() {
+			continue
+		}
+		name := tag.name
+		if name == "" {
+			name = field.Name
+		}
+		switch {
+		case tag.redact:
+			tags = tags.set(Field{prefix + name, redactedValue})
+		case isStruct && nested.IsValid() && depth < maxStructDepth:
+			tags = structTags(tags, nested, prefix+name+".", depth+1)
+		default:
+			tags = tags.set(Any(prefix+name, fieldValue.Interface()))
+		}
+	}
+	return tags
+}
+
+type structTag struct {
+	name      string
+	omitEmpty bool
+	redact    bool // the value is replaced by RedactedMask
+	skip      bool
+}
+
+// parseStructTag returns the name and options of the log or json tag of field.
+func parseStructTag(field reflect.StructField) structTag {
+	tag, found := field.Tag.Lookup("log")
+	if !found {
+		tag = field.Tag.Get("json")
+	}
+	if tag == "-" {
+		return structTag{skip: true}
+	}
+	options := strings.Split(tag, ",")
+	parsed := structTag{name: options[0]}
+	for _, option := range options[1:] {
+		switch option {
+		case "omitempty":
+			parsed.omitEmpty = true
+		case "redact":
+			parsed.redact = true
+		}
+	}
+	return parsed
+}
+
+// nestedStruct dereferences pointers and reports whether value holds a struct
+// that is rendered field by field. Times, errors, Stringers and LogMarshalers
+// are rendered as values. The returned value is invalid for nil pointers.
+func nestedStruct(value reflect.Value) (reflect.Value, bool) {
+	valueType := value.Type()
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	if valueType.Kind() != reflect.Struct || valueType == timeType ||
+		implementsAny(value.Type(), errorType, stringerType, marshalerType) ||
+		implementsAny(valueType, errorType, stringerType, marshalerType) {
+		return reflect.Value{}, false
+	}
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return reflect.Value{}, true
+		}
+		value = value.Elem()
+	}
+	return value, true
+}
+
+func implementsAny(valueType reflect.Type, interfaces ...reflect.Type) bool {
+	for _, iface := range interfaces {
+		if valueType.Implements(iface) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package log
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type address struct {
+	City string `json:"city"`
+	Zip  string `json:"zip,omitempty"`
+}
+
+type Audit struct {
+	CreatedBy string `log:"created_by"`
+}
+
+type user struct {
+	ID      int64    `log:"id"`
+	Address *address `log:"address"`
+}
+
+type request struct {
+	Audit
+	*address
+	Path     string        `log:"path"`
+	Password string        `log:"-"`
+	Token    string        `log:"-" json:"token"`
+	Session  string        `log:"session,redact"`
+	Retries  int           `log:"retries,omitempty"`
+	Elapsed  time.Duration `json:"elapsed"`
+	Started  time.Time     `log:"started"`
+	Err      error         `log:"error"`
+	User     user          `json:"user"`
+	Manager  *user         `log:"manager"`
+	Admin    bool
+	internal string
+}
+
+func TestMergeTagsRendersStructsByTheirTags(t *testing.T) {
+	err := errors.New("failed")
+	context := request{
+		Audit:    Audit{"admin"},
+		address:  &address{City: "Berlin"},
+		Path:     "/orders",
+		Password: "secret",
+		Token:    "secret",
+		Session:  "secret",
+		Elapsed:  time.Second,
+		Started:  testTime,
+		Err:      err,
+		User:     user{42, &address{"Hamburg", "20095"}},
+		internal: "hidden",
+	}
+
+	tags, mergeErr := mergeTags(nil, &context)
+
+	if mergeErr != nil {
+		t.Fatal(mergeErr)
+	}
+	expected := Fields{
+		String("created_by", "admin"),
+		String("city", "Berlin"),
+		String("path", "/orders"),
+		String("session", RedactedMask),
+		Duration("elapsed", time.Second),
+		Time("started", testTime),
+		Err("error", err),
+		Int64("user.id", 42),
+		String("user.address.city", "Hamburg"),
+		String("user.address.zip", "20095"),
+		String("manager", "<nil>"),
+		Bool("Admin", false),
+	}
+	if !tags.Equal(expected) {
+		t.Errorf("expected %v, actual: %v", expected, tags)
+	}
+}
+
+type node struct {
+	Name string `log:"name"`
+	Next *node  `log:"next"`
+}
+
+func TestMergeTagsLimitsTheDepthOfNestedStructs(t *testing.T) {
+	cycle := &node{Name: "a"}
+	cycle.Next = cycle
+
+	tags, err := mergeTags(nil, cycle)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != maxStructDepth+2 {
+		t.Errorf("expected %d tags, actual: %d", maxStructDepth+2, len(tags))
+	}
+}
+
+type link struct {
+	Next *url.URL `log:"next"`
+}
+
+func TestMergeTagsRendersNilPointersWithMethodsAsNil(t *testing.T) {
+	tags, err := mergeTags(nil, link{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Fields{String("next", "<nil>")}
+	if !tags.Equal(expected) {
+		t.Errorf("expected %v, actual: %v", expected, tags)
+	}
+}
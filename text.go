@@ -0,0 +1,194 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The text format escapes with backslashes so that every record stays on one
+// line and its separators stay unambiguous: \\, \t, \n and \r stand for a
+// backslash, tab, line feed and carriage return, other control characters
+// are written as \xHH. Tag keys additionally escape ',' and ':' as \, and \:,
+// tag values escape ',' as \,.
+const (
+	textColumnSeparators = ""
+	textKeySeparators    = ",:"
+	textValueSeparators  = ","
+)
+
+type InvalidTextRecord string
+
+func (err InvalidTextRecord) Error() string {
+	return "invalid text record: " + string(err)
+}
+
+// TextRecord is a record of the TextFormatter read back by ParseText. All tag
+// values are strings.
+type TextRecord struct {
+	Time     string
+	Level    string
+	Function string
+	Message  string
+	Tags     Fields
+}
+
+// ParseText reads a record written by TextFormatter or TextEncoder.
+func ParseText(record string) (*TextRecord, error) {
+	columns, err := splitText(strings.TrimSuffix(record, "\n"), '\t')
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) != 5 {
+		return nil, InvalidTextRecord("expected 5 tab separated columns, found " + strconv.Itoa(len(columns)))
+	}
+	for i := 0; i < 4; i++ {
+		if columns[i], err = unescapeText(columns[i]); err != nil {
+			return nil, err
+		}
+	}
+	parsed := &TextRecord{Time: columns[0], Level: columns[1], Function: columns[2], Message: columns[3], Tags: Fields{}}
+	if len(columns[4]) == 0 {
+		return parsed, nil
+	}
+
+	tags, err := splitText(columns[4], ',')
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		keyValue, err := splitText(tag, ':')
+		if err != nil {
+			return nil, err
+		}
+		if len(keyValue) < 2 {
+			return nil, InvalidTextRecord("missing ':' in tag " + tag)
+		}
+		key, err := unescapeText(keyValue[0])
+		if err != nil {
+			return nil, err
+		}
+		// colons are only escaped in keys, the value starts after the first one
+		value, err := unescapeText(tag[len(keyValue[0])+1:])
+		if err != nil {
+			return nil, err
+		}
+		parsed.Tags = append(parsed.Tags, String(key, value))
+	}
+	return parsed, nil
+}
+
+// appendTextEscaped appends s escaped for the text format. separators are the
+// characters that have to be escaped in addition to backslashes and control
+// characters.
+func appendTextEscaped(dst []byte, s string, separators string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; {
+		case b == '\\':
+			dst = append(dst, '\\', '\\')
+		case b == '\t':
+			dst = append(dst, '\\', 't')
+		case b == '\n':
+			dst = append(dst, '\\', 'n')
+		case b == '\r':
+			dst = append(dst, '\\', 'r')
+		case b < 0x20 || b == 0x7f:
+			dst = append(dst, '\\', 'x', hexDigits[b>>4], hexDigits[b&0xF])
+		case strings.IndexByte(separators, b) >= 0:
+			dst = append(dst, '\\', b)
+		default:
+			dst = append(dst, b)
+		}
+	}
+	return dst
+}
+
+func needsTextEscape(b []byte, separators string) bool {
+	for _, c := range b {
+		if c < 0x20 || c == 0x7f || c == '\\' || strings.IndexByte(separators, c) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeTextTail escapes dst[start:] in place of the unescaped text. It only
+// allocates if escaping is needed.
+func escapeTextTail(dst []byte, start int, separators string) []byte {
+	if !needsTextEscape(dst[start:], separators) {
+		return dst
+	}
+	text := string(dst[start:])
+	return appendTextEscaped(dst[:start], text, separators)
+}
+
+// splitText splits s at the separators that are not escaped.
+func splitText(s string, separator byte) ([]string, error) {
+	parts := []string{}
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 == len(s) {
+				return nil, InvalidTextRecord("trailing backslash")
+			}
+			i++
+		case separator:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:]), nil
+}
+
+func unescapeText(s string) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+	unescaped := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			unescaped = append(unescaped, s[i])
+			continue
+		}
+		i++
+		if i == len(s) {
+			return "", InvalidTextRecord("trailing backslash")
+		}
+		switch s[i] {
+		case '\\', ',', ':':
+			unescaped = append(unescaped, s[i])
+		case 't':
+			unescaped = append(unescaped, '\t')
+		case 'n':
+			unescaped = append(unescaped, '\n')
+		case 'r':
+			unescaped = append(unescaped, '\r')
+		case 'x':
+			if i+2 >= len(s) {
+				return "", InvalidTextRecord("incomplete \\x escape")
+			}
+			high, highOk := unhex(s[i+1])
+			low, lowOk := unhex(s[i+2])
+			if !highOk || !lowOk {
+				return "", InvalidTextRecord("invalid \\x escape")
+			}
+			unescaped = append(unescaped, high<<4|low)
+			i += 2
+		default:
+			return "", InvalidTextRecord("invalid escape \\" + string(s[i]))
+		}
+	}
+	return string(unescaped), nil
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextFormatterShouldEscapeSeparators(t *testing.T) {
+	resultString := TextFormatter(testTime, "INFO", "tab\there\nnext line", Fields{
+		String("function", "func\tname"),
+		String("comma", "a,b"),
+		String("colon", "a:b"),
+		String("key:with,separators", "value"),
+		String("backslash", `C:\dir\`),
+		String("control", "\x00\x7f\r"),
+	}, "2006")
+
+	expected := testTime.Format("2006") + "\tINFO\tfunc\\tname\ttab\\there\\nnext line\t" +
+		`backslash:C:\\dir\\,colon:a:b,comma:a\,b,control:\x00\x7f\r,key\:with\,separators:value`
+	if resultString != expected {
+		t.Errorf("expected %q, actual: %q", expected, resultString)
+	}
+}
+
+func TestParseTextShouldReadTheRecordsOfTextFormatterBack(t *testing.T) {
+	fields := Fields{
+		String("function", "func\tname"),
+		String("comma", "a,b,"),
+		String("colon", "a:b:"),
+		String("key:with,separators\\", "value\\"),
+		String("empty", ""),
+		String("multi", "line 1\nline 2\r\n"),
+		Int("count", 3),
+		Array("list", StringValue("x,y"), StringValue("z")),
+		Object("object", String("a", "1,2")),
+	}
+	resultString := TextFormatter(testTime, "INFO", "message\twith\\tabs", fields, "2006-01-02\t15")
+
+	record, err := ParseText(resultString + "\n")
+	if err != nil {
+		t.Fatalf("ParseText failed with error: %s, output: %q", err, resultString)
+	}
+	if record.Time != testTime.Format("2006-01-02\t15") || record.Level != "INFO" ||
+		record.Function != "func\tname" || record.Message != "message\twith\\tabs" {
+		t.Errorf("unexpected columns %#v", record)
+	}
+	expected := Fields{
+		String("colon", "a:b:"),
+		String("comma", "a,b,"),
+		String("count", "3"),
+		String("empty", ""),
+		String("key:with,separators\\", "value\\"),
+		String("list", "[x,y z]"),
+		String("multi", "line 1\nline 2\r\n"),
+		String("object.a", "1,2"),
+	}
+	if !record.Tags.Equal(expected) {
+		t.Errorf("expected %v, actual: %v", expected, record.Tags)
+	}
+}
+
+func TestParseTextShouldReadRecordsWithoutTags(t *testing.T) {
+	record, err := ParseText(TextFormatter(testTime, "DEBUG", "message", Fields{String("function", "main")}, "2006"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Function != "main" || record.Message != "message" || len(record.Tags) != 0 {
+		t.Errorf("unexpected record %#v", record)
+	}
+}
+
+func TestTextEncoderShouldEscapeLikeTextFormatter(t *testing.T) {
+	now := time.Now()
+	fields := Fields{String("a,b", "c\td"), Duration("elapsed", time.Second)}
+	encoder := TextEncoder{}
+
+	encoded := encoder.AppendHeader(nil, now, "2006", "INFO", "x\ny", "f,g")
+	for _, field := range fields {
+		encoded = encoder.AppendField(encoded, field)
+	}
+	encoded = encoder.AppendFooter(encoded)
+
+	record, err := ParseText(string(encoded))
+	if err != nil {
+		t.Fatalf("ParseText failed with error: %s, output: %q", err, encoded)
+	}
+	expected := Fields{String("a,b", "c\td"), String("elapsed", "1s")}
+	if record.Message != "x\ny" || record.Function != "f,g" || !record.Tags.Equal(expected) {
+		t.Errorf("unexpected record %#v", record)
+	}
+}
+
+func TestParseTextShouldRejectInvalidRecords(t *testing.T) {
+	for _, record := range []string{
+		"2017\tINFO\tfunction\tmessage",
+		"2017\tINFO\tfunction\tmessage\ttag:value\textra",
+		"2017\tINFO\tfunction\tmessage\ttag",
+		"2017\tINFO\tfunction\tmess\\age\t",
+		"2017\tINFO\tfunction\tmessage\ttag:\\x4",
+		"2017\tINFO\tfunction\tmessage\ttag:value\\",
+	} {
+		if _, err := ParseText(record); err == nil {
+			t.Errorf("expected an error for %q", record)
+		} else if _, ok := err.(InvalidTextRecord); !ok {
+			t.Errorf("expected InvalidTextRecord for %q, actual: %#v", record, err)
+		}
+	}
+}
+
+func FuzzTextFormatter(f *testing.F) {
+	f.Add("INFO", "message", "function", "key", "value")
+	f.Add("DEBUG", "tab\tnewline\n", "a,b", "key:,", "\\,\x00")
+	f.Add("L", "\\t", "\\", "\\x", ":,\\")
+	f.Fuzz(func(t *testing.T, level string, message string, function string, key string, value string) {
+		if key == "" || key == "function" {
+			t.Skip()
+		}
+		resultString := TextFormatter(testTime, level, message, Fields{String("function", function), String(key, value)}, "2006")
+
+		if strings.ContainsAny(resultString, "\n\r") {
+			t.Fatalf("expected output on a single line, actual: %q", resultString)
+		}
+		record, err := ParseText(resultString)
+		if err != nil {
+			t.Fatalf("ParseText failed with error: %s, output: %q", err, resultString)
+		}
+		if record.Level != level || record.Message != message || record.Function != function {
+			t.Errorf("unexpected columns %#v, output: %q", record, resultString)
+		}
+		if !record.Tags.Equal(Fields{String(key, value)}) {
+			t.Errorf("expected tag %q:%q, actual: %v", key, value, record.Tags)
+		}
+	})
+}